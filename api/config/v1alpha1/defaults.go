@@ -0,0 +1,51 @@
+package v1alpha1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultMetricsBindAddress = ":8080"
+	defaultProbeBindAddress   = ":8081"
+	defaultWebhookPort        = 9443
+	defaultInitContainerImage = "initcontainer:1"
+	defaultNodepoolLabel      = "agentpool"
+
+	// events exist for 1 hour by default in k8s, track for a little longer
+	defaultEventTrackingTTL = time.Minute * 75
+
+	// buckets from 2 secs up to 60mins over 28 increments
+	defaultBucketStart  = 2
+	defaultBucketFactor = 1.32
+	defaultBucketCount  = 28
+)
+
+// SetDefaults fills in every unset field of the configuration
+func SetDefaults(c *OperatorConfig) {
+	c.APIVersion = GroupVersion.String()
+	c.Kind = OperatorConfigKind
+
+	if c.Metrics.BindAddress == "" {
+		c.Metrics.BindAddress = defaultMetricsBindAddress
+	}
+	if c.Metrics.TimingBuckets == (ExponentialBuckets{}) {
+		c.Metrics.TimingBuckets = ExponentialBuckets{Start: defaultBucketStart, Factor: defaultBucketFactor, Count: defaultBucketCount}
+	}
+	if c.Health.ProbeBindAddress == "" {
+		c.Health.ProbeBindAddress = defaultProbeBindAddress
+	}
+	if c.Webhook.Port == 0 {
+		c.Webhook.Port = defaultWebhookPort
+	}
+	if c.InitContainer.Image == "" {
+		c.InitContainer.Image = defaultInitContainerImage
+	}
+	if c.NodepoolLabel == "" {
+		c.NodepoolLabel = defaultNodepoolLabel
+	}
+	if c.EventTrackingTTL.Duration == 0 {
+		c.EventTrackingTTL = metav1.Duration{Duration: defaultEventTrackingTTL}
+	}
+}
@@ -0,0 +1,14 @@
+// Package v1alpha1 contains the configuration file format of the operator manager
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	// GroupVersion is the group version of the operator configuration file
+	GroupVersion = schema.GroupVersion{Group: "config.cronprimer.local", Version: "v1alpha1"}
+)
+
+// OperatorConfigKind is the kind of the operator configuration file
+const OperatorConfigKind = "OperatorConfig"
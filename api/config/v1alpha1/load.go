@@ -0,0 +1,39 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Load reads a configuration file and fills in defaults, an empty path gives the default configuration
+func Load(path string) (*OperatorConfig, error) {
+	if path == "" {
+		config := &OperatorConfig{}
+		SetDefaults(config)
+		return config, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// Decode parses a configuration file and fills in defaults, unknown fields are rejected
+func Decode(data []byte) (*OperatorConfig, error) {
+	config := &OperatorConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse operator config: %s", err)
+	}
+
+	if config.APIVersion != GroupVersion.String() || config.Kind != OperatorConfigKind {
+		return nil, fmt.Errorf("unsupported operator config %s %s, expected %s %s",
+			config.APIVersion, config.Kind, GroupVersion.String(), OperatorConfigKind)
+	}
+
+	SetDefaults(config)
+	return config, nil
+}
@@ -0,0 +1,83 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode_FillsDefaultsAroundSetFields(t *testing.T) {
+	config, err := Decode([]byte(`
+apiVersion: config.cronprimer.local/v1alpha1
+kind: OperatorConfig
+nodepoolLabel: kubernetes.azure.com/agentpool
+eventTrackingTTL: 2h
+webhook:
+  enabled: true
+`))
+
+	require.NoError(t, err)
+	require.NoError(t, config.Validate())
+	require.Equal(t, "kubernetes.azure.com/agentpool", config.NodepoolLabel)
+	require.Equal(t, time.Hour*2, config.EventTrackingTTL.Duration)
+	require.True(t, config.Webhook.Enabled)
+	require.Equal(t, 9443, config.Webhook.Port)
+	require.Equal(t, ":8081", config.Health.ProbeBindAddress)
+	require.Equal(t, ExponentialBuckets{Start: 2, Factor: 1.32, Count: 28}, config.Metrics.TimingBuckets)
+}
+
+func TestDecode_RejectsUnknownVersionsAndFields(t *testing.T) {
+	scenarios := map[string]string{
+		"wrong version": "apiVersion: config.cronprimer.local/v2\nkind: OperatorConfig\n",
+		"wrong kind":    "apiVersion: config.cronprimer.local/v1alpha1\nkind: Config\n",
+		"unknown field": "apiVersion: config.cronprimer.local/v1alpha1\nkind: OperatorConfig\nnodepool: agentpool\n",
+	}
+
+	for name, data := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			_, err := Decode([]byte(data))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	config, err := Load("")
+	require.NoError(t, err)
+
+	config.Namespaces = []string{"team-a", "team-a", "Team_B"}
+	config.Health.ProbeBindAddress = "8081"
+	config.Webhook.Port = 70000
+	config.Metrics.TimingBuckets.Factor = 1
+	config.EventTrackingTTL.Duration = time.Second
+	config.Tracing.Endpoint = "http://collector:4318"
+
+	err = config.Validate()
+
+	require.Error(t, err)
+	for _, field := range []string{"namespaces[1]", "namespaces[2]", "health.probeBindAddress", "webhook.port", "metrics.timingBuckets.factor", "eventTrackingTTL", "tracing.endpoint"} {
+		require.Contains(t, err.Error(), field)
+	}
+}
+
+func TestValidate_CostPrices(t *testing.T) {
+	config, err := Decode([]byte(`
+apiVersion: config.cronprimer.local/v1alpha1
+kind: OperatorConfig
+cost:
+  prices:
+  - nodepool: gpu
+    cpuCoreHour: -1
+  - nodepool: gpu
+    memoryGBHour: 0.005
+`))
+	require.NoError(t, err)
+
+	err = config.Validate()
+
+	require.Error(t, err)
+	for _, field := range []string{"cost.currency", "cost.prices[1].nodepool", "cost.prices[0].cpuCoreHour"} {
+		require.Contains(t, err.Error(), field)
+	}
+}
@@ -0,0 +1,165 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OperatorConfig is the configuration file of the operator manager. Values set through flags or the
+// INIT_CONTAINER_IMAGE environment variable take precedence over the file.
+type OperatorConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// DryRun makes the operator report what it would do to cronjobs through events, status and metrics
+	// without writing them, and leave primed pods and jobs untouched
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Namespaces restricts the manager to a set of namespaces, every namespace is watched when empty
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Metrics configures the prometheus metrics endpoint
+	// +optional
+	Metrics MetricsConfig `json:"metrics,omitempty"`
+
+	// Health configures the readiness and liveness probes
+	// +optional
+	Health HealthConfig `json:"health,omitempty"`
+
+	// Webhook configures the validating webhook server
+	// +optional
+	Webhook WebhookConfig `json:"webhook,omitempty"`
+
+	// LeaderElection configures leader election between manager replicas
+	// +optional
+	LeaderElection LeaderElectionConfig `json:"leaderElection,omitempty"`
+
+	// InitContainer configures the warm-up container injected into primed pods
+	// +optional
+	InitContainer InitContainerConfig `json:"initContainer,omitempty"`
+
+	// NodepoolLabel is the node selector key used to find the nodepool a pod targets. Can be changed without a restart.
+	// +optional
+	NodepoolLabel string `json:"nodepoolLabel,omitempty"`
+
+	// EventTrackingTTL is how long the last processed event of a pod is remembered, it should outlive the
+	// events themselves. Can be changed without a restart.
+	// +optional
+	EventTrackingTTL metav1.Duration `json:"eventTrackingTTL,omitempty"`
+
+	// Cost prices the node time reserved by warm-up pods while they wait. Can be changed without a restart.
+	// +optional
+	Cost CostConfig `json:"cost,omitempty"`
+
+	// Tracing exports a trace of each primed run through OTLP
+	// +optional
+	Tracing TracingConfig `json:"tracing,omitempty"`
+}
+
+// MetricsConfig configures the prometheus metrics endpoint
+type MetricsConfig struct {
+	// BindAddress is the address the metrics endpoint binds to, "0" disables it
+	// +optional
+	BindAddress string `json:"bindAddress,omitempty"`
+
+	// TimingBuckets are the buckets of the transition time histograms
+	// +optional
+	TimingBuckets ExponentialBuckets `json:"timingBuckets,omitempty"`
+}
+
+// ExponentialBuckets describes histogram buckets where each bucket is a factor larger than the last
+type ExponentialBuckets struct {
+	// Start is the upper bound of the first bucket in seconds
+	Start float64 `json:"start,omitempty"`
+	// Factor is how much larger each bucket is than the one before
+	Factor float64 `json:"factor,omitempty"`
+	// Count is the number of buckets
+	Count int `json:"count,omitempty"`
+}
+
+// HealthConfig configures the readiness and liveness probes
+type HealthConfig struct {
+	// ProbeBindAddress is the address the probe and preview endpoints bind to
+	// +optional
+	ProbeBindAddress string `json:"probeBindAddress,omitempty"`
+}
+
+// WebhookConfig configures the validating webhook server
+type WebhookConfig struct {
+	// Enabled serves the validating webhook, serving certificates must be mounted for the webhook server
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Port is the port the webhook server listens on
+	// +optional
+	Port int `json:"port,omitempty"`
+}
+
+// LeaderElectionConfig configures leader election between manager replicas
+type LeaderElectionConfig struct {
+	// LeaderElect ensures there is only one active manager
+	// +optional
+	LeaderElect bool `json:"leaderElect,omitempty"`
+}
+
+// InitContainerConfig configures the warm-up container injected into primed pods
+type InitContainerConfig struct {
+	// Image is the warm-up container image
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// ImagePullPolicy of the warm-up container, IfNotPresent when unset
+	// +optional
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// ImagePullSecrets are added to primed pods so the warm-up image can be pulled from a private registry.
+	// Pull secrets already on the pod are kept.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// Resources are merged over the built in requests of 10m CPU and 64Mi memory and limit of 128Mi memory
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// SecurityContext is merged over the built in one, which passes the restricted Pod Security profile
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+}
+
+// TracingConfig configures the export of run traces
+type TracingConfig struct {
+	// Endpoint is the host:port of the OTLP/HTTP collector traces are sent to, tracing is off when empty
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Insecure sends traces over plain HTTP rather than HTTPS
+	// +optional
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// CostConfig converts the node time reserved by warm-up pods into currency
+type CostConfig struct {
+	// Currency the prices are in, required when there are prices
+	// +optional
+	Currency string `json:"currency,omitempty"`
+
+	// Prices of the nodepools, a price without a nodepool applies to every nodepool without its own
+	// +optional
+	Prices []NodepoolPrice `json:"prices,omitempty"`
+}
+
+// NodepoolPrice is what an hour of reserved resources costs on a nodepool
+type NodepoolPrice struct {
+	// Nodepool the price applies to, every other nodepool when empty
+	// +optional
+	Nodepool string `json:"nodepool,omitempty"`
+
+	// CPUCoreHour is the price of one CPU core for an hour
+	// +optional
+	CPUCoreHour float64 `json:"cpuCoreHour,omitempty"`
+
+	// MemoryGBHour is the price of one GB of memory for an hour
+	// +optional
+	MemoryGBHour float64 `json:"memoryGBHour,omitempty"`
+}
@@ -0,0 +1,107 @@
+package v1alpha1
+
+import (
+	"net"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Validate checks a defaulted configuration, returning every problem found
+func (c *OperatorConfig) Validate() error {
+	errs := field.ErrorList{}
+
+	seen := map[string]bool{}
+	for i, namespace := range c.Namespaces {
+		path := field.NewPath("namespaces").Index(i)
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			errs = append(errs, field.Invalid(path, namespace, msg))
+		}
+		if seen[namespace] {
+			errs = append(errs, field.Duplicate(path, namespace))
+		}
+		seen[namespace] = true
+	}
+
+	// "0" is how controller-runtime turns the metrics endpoint off
+	if c.Metrics.BindAddress != "0" {
+		errs = append(errs, validateBindAddress(field.NewPath("metrics", "bindAddress"), c.Metrics.BindAddress)...)
+	}
+	errs = append(errs, validateBuckets(field.NewPath("metrics", "timingBuckets"), c.Metrics.TimingBuckets)...)
+	errs = append(errs, validateBindAddress(field.NewPath("health", "probeBindAddress"), c.Health.ProbeBindAddress)...)
+
+	if c.Webhook.Port < 1 || c.Webhook.Port > 65535 {
+		errs = append(errs, field.Invalid(field.NewPath("webhook", "port"), c.Webhook.Port, "must be between 1 and 65535"))
+	}
+
+	if c.InitContainer.Image == "" {
+		errs = append(errs, field.Required(field.NewPath("initContainer", "image"), ""))
+	}
+	switch c.InitContainer.ImagePullPolicy {
+	case "", corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever:
+	default:
+		errs = append(errs, field.NotSupported(field.NewPath("initContainer", "imagePullPolicy"), c.InitContainer.ImagePullPolicy,
+			[]string{string(corev1.PullAlways), string(corev1.PullIfNotPresent), string(corev1.PullNever)}))
+	}
+
+	for _, msg := range validation.IsQualifiedName(c.NodepoolLabel) {
+		errs = append(errs, field.Invalid(field.NewPath("nodepoolLabel"), c.NodepoolLabel, msg))
+	}
+
+	if c.EventTrackingTTL.Duration < time.Minute {
+		errs = append(errs, field.Invalid(field.NewPath("eventTrackingTTL"), c.EventTrackingTTL.Duration.String(), "must be at least 1m"))
+	}
+
+	errs = append(errs, validateCost(field.NewPath("cost"), c.Cost)...)
+	if c.Tracing.Endpoint != "" {
+		errs = append(errs, validateBindAddress(field.NewPath("tracing", "endpoint"), c.Tracing.Endpoint)...)
+	}
+
+	return errs.ToAggregate()
+}
+
+func validateBindAddress(path *field.Path, address string) field.ErrorList {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return field.ErrorList{field.Invalid(path, address, err.Error())}
+	}
+	return nil
+}
+
+func validateBuckets(path *field.Path, buckets ExponentialBuckets) field.ErrorList {
+	errs := field.ErrorList{}
+	if buckets.Start <= 0 {
+		errs = append(errs, field.Invalid(path.Child("start"), buckets.Start, "must be greater than 0"))
+	}
+	if buckets.Factor <= 1 {
+		errs = append(errs, field.Invalid(path.Child("factor"), buckets.Factor, "must be greater than 1"))
+	}
+	if buckets.Count < 1 {
+		errs = append(errs, field.Invalid(path.Child("count"), buckets.Count, "must be at least 1"))
+	}
+	return errs
+}
+
+func validateCost(path *field.Path, cost CostConfig) field.ErrorList {
+	errs := field.ErrorList{}
+	if len(cost.Prices) > 0 && cost.Currency == "" {
+		errs = append(errs, field.Required(path.Child("currency"), "needed to label the cost of the prices"))
+	}
+
+	seen := map[string]bool{}
+	for i, price := range cost.Prices {
+		pricePath := path.Child("prices").Index(i)
+		if seen[price.Nodepool] {
+			errs = append(errs, field.Duplicate(pricePath.Child("nodepool"), price.Nodepool))
+		}
+		seen[price.Nodepool] = true
+		if price.CPUCoreHour < 0 {
+			errs = append(errs, field.Invalid(pricePath.Child("cpuCoreHour"), price.CPUCoreHour, "must not be negative"))
+		}
+		if price.MemoryGBHour < 0 {
+			errs = append(errs, field.Invalid(pricePath.Child("memoryGBHour"), price.MemoryGBHour, "must not be negative"))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,52 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Exclusion is a day or span of days on which prescaled runs are skipped
+type Exclusion struct {
+	// Start is the first day excluded, as YYYY-MM-DD
+	// +kubebuilder:validation:Pattern=`^\d{4}-\d{2}-\d{2}$`
+	Start string `json:"start"`
+	// End is the last day excluded, as YYYY-MM-DD, only Start is excluded when unset
+	// +kubebuilder:validation:Pattern=`^\d{4}-\d{2}-\d{2}$`
+	// +optional
+	End string `json:"end,omitempty"`
+	// Reason is shown in the status of the PreScaledCronJobs that skip a run, such as the name of the holiday
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// ExclusionCalendarSpec lists the days on which the PreScaledCronJobs referencing the calendar don't run
+type ExclusionCalendarSpec struct {
+	// TimeZone the days start and end in, such as Europe/London, UTC when unset
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+	// Exclusions are the days and spans of days to skip
+	Exclusions []Exclusion `json:"exclusions"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// ExclusionCalendar is the Schema for the exclusioncalendars API
+type ExclusionCalendar struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ExclusionCalendarSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ExclusionCalendarList contains a list of ExclusionCalendar
+type ExclusionCalendarList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ExclusionCalendar `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ExclusionCalendar{}, &ExclusionCalendarList{})
+}
@@ -2,6 +2,7 @@ package v1alpha1
 
 import (
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -10,18 +11,276 @@ import (
 
 // PreScaledCronJobSpec defines the desired state of PreScaledCronJob
 type PreScaledCronJobSpec struct {
+	// WarmUpDuration is how long before each run the cluster is warmed up. The primer fires on the whole minute
+	// at or before the warm-up starts, the workload is still released at its scheduled time. Takes precedence
+	// over WarmUpTimeMins.
+	// +optional
+	WarmUpDuration *metav1.Duration `json:"warmUpDuration,omitempty"`
+
+	// WarmUpTimeMins is the warm-up in whole minutes, used when WarmUpDuration is unset.
+	// Deprecated: use WarmUpDuration.
 	WarmUpTimeMins int                  `json:"warmUpTimeMins,omitempty"`
 	PrimerSchedule string               `json:"primerSchedule,omitempty"`
 	CronJob        batchv1beta1.CronJob `json:"cronJob,omitempty"`
+
+	// WarmUpContainer overrides parts of the injected warm-up container, unset fields use the operator defaults
+	// +optional
+	WarmUpContainer *WarmUpContainerTemplate `json:"warmUpContainer,omitempty"`
+
+	// ReleaseMode controls how the pods of a job are released from warm-up, Individual when unset
+	// +kubebuilder:validation:Enum=Individual;Barrier
+	// +optional
+	ReleaseMode ReleaseMode `json:"releaseMode,omitempty"`
+
+	// BarrierTimeoutSeconds is how long past the workload time pods are held waiting for the rest of their job
+	// in Barrier mode, 300 when unset
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	BarrierTimeoutSeconds *int32 `json:"barrierTimeoutSeconds,omitempty"`
+
+	// MaxLateness is how far past the workload time a run may still start, runs which finish warming up later
+	// than this are handled by the LatenessPolicy. Unset runs start however late they are. In Barrier release mode
+	// a run is only checked once its pods have been released.
+	// +optional
+	MaxLateness *metav1.Duration `json:"maxLateness,omitempty"`
+
+	// LatenessPolicy decides what happens to a run later than MaxLateness, Skip when unset
+	// +kubebuilder:validation:Enum=Skip;Fail
+	// +optional
+	LatenessPolicy LatenessPolicy `json:"latenessPolicy,omitempty"`
+
+	// PrimerSpread staggers the primers of PreScaledCronJobs sharing a schedule so they don't all scale up their
+	// nodepool in the same minute. Each primer fires up to this much earlier than its warm-up needs, by an offset
+	// in whole minutes derived from the object's UID. The workload is still released at its scheduled time.
+	// Ignored when PrimerSchedule is set.
+	// +optional
+	PrimerSpread *metav1.Duration `json:"primerSpread,omitempty"`
+
+	// ExclusionCalendars names the ExclusionCalendars whose days are skipped, neither the primer nor the workload
+	// runs for a workload time on an excluded day
+	// +optional
+	ExclusionCalendars []string `json:"exclusionCalendars,omitempty"`
+
+	// Schedules runs the job on several schedules, each primed by a cronjob of its own. When set the schedule of
+	// CronJob and the top level PrimerSchedule are ignored, the top level warm-up is used by entries without one.
+	// +optional
+	Schedules []ScheduleEntry `json:"schedules,omitempty"`
+}
+
+// ScheduleEntry is one of the schedules of a PreScaledCronJob
+type ScheduleEntry struct {
+	// Name identifies the schedule, it suffixes the name of its autogenerated cronjob and labels its pods
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=20
+	Name string `json:"name"`
+	// Schedule is the cron schedule of the workload
+	Schedule string `json:"schedule"`
+	// WarmUpDuration is how long before each run of this schedule the cluster is warmed up, the warm-up of the
+	// PreScaledCronJob when unset
+	// +optional
+	WarmUpDuration *metav1.Duration `json:"warmUpDuration,omitempty"`
+	// PrimerSchedule is used in place of the primer schedule generated for this schedule
+	// +optional
+	PrimerSchedule string `json:"primerSchedule,omitempty"`
+}
+
+// LatenessPolicy is what happens to a run which started warming up too late
+type LatenessPolicy string
+
+const (
+	// SkipLateRun deletes the job, the run is missed the same way a cronjob misses a run past its starting deadline
+	SkipLateRun LatenessPolicy = "Skip"
+	// FailLateRun fails the job so the missed run shows up in the job history and alerts on failed jobs
+	FailLateRun LatenessPolicy = "Fail"
+)
+
+// ReleaseMode is how the pods of a job are released from warm-up
+type ReleaseMode string
+
+const (
+	// IndividualRelease releases each pod at the workload time on its own
+	IndividualRelease ReleaseMode = "Individual"
+	// BarrierRelease holds every pod of a job until all of them have been scheduled or the barrier deadline passes
+	BarrierRelease ReleaseMode = "Barrier"
+)
+
+// WarmUpContainerTemplate describes the parts of the warm-up container which can be changed. Its pull secrets
+// come from the pod it is injected into.
+type WarmUpContainerTemplate struct {
+	// Image of the warm-up container, for example a mirror in an air-gapped registry
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// ImagePullPolicy of the warm-up container
+	// +optional
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// Resources are merged with the defaults by resource name
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// SecurityContext is merged with the defaults field by field
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
 }
 
 // PreScaledCronJobStatus defines the observed state of PreScaledCronJob
 type PreScaledCronJobStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Conditions describe problems the operator found with the PreScaledCronJob
+	// +optional
+	Conditions []PreScaledCronJobCondition `json:"conditions,omitempty"`
+
+	// DryRun is what the operator would do to the autogenerated cronjobs, only set while it runs in dry-run mode
+	// +optional
+	DryRun *DryRunStatus `json:"dryRun,omitempty"`
+
+	// Retries counts the reconciles which have failed in a row, it is reset by the next successful one and when
+	// the PreScaledCronJob changes
+	// +optional
+	Retries int32 `json:"retries,omitempty"`
+
+	// RetriesGeneration is the generation of the PreScaledCronJob the retries were counted for
+	// +optional
+	RetriesGeneration int64 `json:"retriesGeneration,omitempty"`
+
+	// LastError is the error of the last failed reconcile
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// LastErrorTime is when the last reconcile failed
+	// +optional
+	LastErrorTime *metav1.Time `json:"lastErrorTime,omitempty"`
+
+	// PrimerOffset is how much earlier the primer fires because of the PrimerSpread
+	// +optional
+	PrimerOffset *metav1.Duration `json:"primerOffset,omitempty"`
+
+	// UpcomingSkips are the next runs which fall on a day of one of the ExclusionCalendars
+	// +optional
+	UpcomingSkips []SkippedRun `json:"upcomingSkips,omitempty"`
+
+	// LastRun is the outcome of the most recent job to finish
+	// +optional
+	LastRun *RunOutcome `json:"lastRun,omitempty"`
+
+	// SucceededRuns counts the jobs which have completed since the operator started tracking them
+	// +optional
+	SucceededRuns int32 `json:"succeededRuns,omitempty"`
+
+	// FailedRuns counts the jobs which have failed since the operator started tracking them
+	// +optional
+	FailedRuns int32 `json:"failedRuns,omitempty"`
+}
+
+// RunResult is whether a prescaled run's job completed or failed
+type RunResult string
+
+const (
+	// RunSucceeded means the job completed
+	RunSucceeded RunResult = "Succeeded"
+	// RunFailed means the job failed, for example by running out of retries or being failed for lateness
+	RunFailed RunResult = "Failed"
+)
+
+// RunOutcome describes how a prescaled run's job finished
+type RunOutcome struct {
+	// JobName is the job of the run
+	JobName string `json:"jobName"`
+	// Schedule is the name of the schedule entry the run belongs to, empty without Schedules
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+	// Result is whether the job completed or failed
+	Result RunResult `json:"result"`
+	// WorkloadTime is when the workload was meant to start
+	// +optional
+	WorkloadTime *metav1.Time `json:"workloadTime,omitempty"`
+	// FinishTime is when the job completed or failed
+	FinishTime metav1.Time `json:"finishTime"`
+	// Duration is how long the run took from its workload time, or from the job starting when that isn't known
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// Retries is how many of the job's pods failed
+	// +optional
+	Retries int32 `json:"retries,omitempty"`
+	// WarmUpPercent is the share of the node time held by the job's pods which was spent warming up, unset when
+	// the pods had gone before the job finished
+	// +optional
+	WarmUpPercent *int32 `json:"warmUpPercent,omitempty"`
+}
+
+// SkippedRun is a run which won't happen because its day is excluded
+type SkippedRun struct {
+	// WorkloadTime is when the workload would have started
+	WorkloadTime metav1.Time `json:"workloadTime"`
+	// Calendar is the ExclusionCalendar excluding the day
+	Calendar string `json:"calendar"`
+	// Reason is the reason given by the calendar
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Schedule is the name of the schedule entry the run belongs to, empty without Schedules
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+}
+
+// DryRunAction is a change the operator would make to an autogenerated cronjob
+type DryRunAction string
+
+const (
+	// DryRunCreate means the cronjob doesn't exist and would be created
+	DryRunCreate DryRunAction = "Create"
+	// DryRunUpdate means the cronjob differs from the generated one and would be updated
+	DryRunUpdate DryRunAction = "Update"
+	// DryRunNoOp means the cronjob matches the generated one
+	DryRunNoOp DryRunAction = "None"
+	// DryRunConflict means a cronjob with the generated name exists which the operator doesn't own
+	DryRunConflict DryRunAction = "Conflict"
+	// DryRunDelete means the cronjob would be removed with its jobs, either because the prescaledcronjob is being
+	// deleted or because no schedule generates it any more
+	DryRunDelete DryRunAction = "Delete"
+)
+
+// DryRunStatus describes what the operator would do to the autogenerated cronjobs
+type DryRunStatus struct {
+	// CronJobs holds the decision for each cronjob the operator would generate or delete
+	CronJobs []DryRunCronJob `json:"cronJobs"`
+	// +optional
+	LastEvaluatedTime metav1.Time `json:"lastEvaluatedTime,omitempty"`
+}
+
+// DryRunCronJob is what the operator would do to one autogenerated cronjob
+type DryRunCronJob struct {
+	Action      DryRunAction `json:"action"`
+	CronJobName string       `json:"cronJobName"`
+	// ObjectHash is the hash of the generated cronjob, compared with the one stored on the existing cronjob
+	// +optional
+	ObjectHash string `json:"objectHash,omitempty"`
+}
+
+// PreScaledCronJobConditionType is the type of a PreScaledCronJob condition
+type PreScaledCronJobConditionType string
+
+const (
+	// PolicyViolation is true when the PreScaledCronJob breaks a PreScalePolicy, its cronjob is suspended until it's fixed
+	PolicyViolation PreScaledCronJobConditionType = "PolicyViolation"
+	// PreflightFailed is true when the checks made shortly before the primer fires found the cronjob or its
+	// nodepool not ready
+	PreflightFailed PreScaledCronJobConditionType = "PreflightFailed"
+)
+
+// PreScaledCronJobCondition describes the state of a PreScaledCronJob at a certain point
+type PreScaledCronJobCondition struct {
+	Type   PreScaledCronJobConditionType `json:"type"`
+	Status corev1.ConditionStatus        `json:"status"`
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 
 // PreScaledCronJob is the Schema for the prescaledcronjobs API
 type PreScaledCronJob struct {
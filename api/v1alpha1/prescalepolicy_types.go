@@ -0,0 +1,55 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PreScaleMode is a way the operator can warm up a nodepool
+// +kubebuilder:validation:Enum=InitContainer
+type PreScaleMode string
+
+// InitContainerMode holds the workload in an injected init container until its scheduled time
+const InitContainerMode PreScaleMode = "InitContainer"
+
+// PreScalePolicySpec defines the limits applied to PreScaledCronJobs in the selected namespaces
+type PreScalePolicySpec struct {
+	// NamespaceSelector picks the namespaces the policy applies to, an empty selector matches every namespace
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// MaxWarmUpMinutes is the longest a primer may run ahead of its workload
+	// +optional
+	MaxWarmUpMinutes *int `json:"maxWarmUpMinutes,omitempty"`
+	// AllowedNodepools lists the nodepools PreScaledCronJobs may target, empty allows any nodepool
+	// +optional
+	AllowedNodepools []string `json:"allowedNodepools,omitempty"`
+	// MaxConcurrentWarmPods limits how many pods may be warming up at once across a namespace
+	// +optional
+	MaxConcurrentWarmPods *int32 `json:"maxConcurrentWarmPods,omitempty"`
+	// AllowedModes lists the pre-scaling modes which are permitted, empty allows every mode
+	// +optional
+	AllowedModes []PreScaleMode `json:"allowedModes,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// PreScalePolicy is the Schema for the prescalepolicies API
+type PreScalePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PreScalePolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PreScalePolicyList contains a list of PreScalePolicy
+type PreScalePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PreScalePolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PreScalePolicy{}, &PreScalePolicyList{})
+}
@@ -5,16 +5,245 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DryRunCronJob) DeepCopyInto(out *DryRunCronJob) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DryRunCronJob.
+func (in *DryRunCronJob) DeepCopy() *DryRunCronJob {
+	if in == nil {
+		return nil
+	}
+	out := new(DryRunCronJob)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DryRunStatus) DeepCopyInto(out *DryRunStatus) {
+	*out = *in
+	if in.CronJobs != nil {
+		in, out := &in.CronJobs, &out.CronJobs
+		*out = make([]DryRunCronJob, len(*in))
+		copy(*out, *in)
+	}
+	in.LastEvaluatedTime.DeepCopyInto(&out.LastEvaluatedTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DryRunStatus.
+func (in *DryRunStatus) DeepCopy() *DryRunStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DryRunStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Exclusion) DeepCopyInto(out *Exclusion) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Exclusion.
+func (in *Exclusion) DeepCopy() *Exclusion {
+	if in == nil {
+		return nil
+	}
+	out := new(Exclusion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExclusionCalendar) DeepCopyInto(out *ExclusionCalendar) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExclusionCalendar.
+func (in *ExclusionCalendar) DeepCopy() *ExclusionCalendar {
+	if in == nil {
+		return nil
+	}
+	out := new(ExclusionCalendar)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ExclusionCalendar) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExclusionCalendarList) DeepCopyInto(out *ExclusionCalendarList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ExclusionCalendar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExclusionCalendarList.
+func (in *ExclusionCalendarList) DeepCopy() *ExclusionCalendarList {
+	if in == nil {
+		return nil
+	}
+	out := new(ExclusionCalendarList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ExclusionCalendarList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExclusionCalendarSpec) DeepCopyInto(out *ExclusionCalendarSpec) {
+	*out = *in
+	if in.Exclusions != nil {
+		in, out := &in.Exclusions, &out.Exclusions
+		*out = make([]Exclusion, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExclusionCalendarSpec.
+func (in *ExclusionCalendarSpec) DeepCopy() *ExclusionCalendarSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExclusionCalendarSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreScalePolicy) DeepCopyInto(out *PreScalePolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreScalePolicy.
+func (in *PreScalePolicy) DeepCopy() *PreScalePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(PreScalePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PreScalePolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreScalePolicyList) DeepCopyInto(out *PreScalePolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PreScalePolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreScalePolicyList.
+func (in *PreScalePolicyList) DeepCopy() *PreScalePolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(PreScalePolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PreScalePolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreScalePolicySpec) DeepCopyInto(out *PreScalePolicySpec) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxWarmUpMinutes != nil {
+		in, out := &in.MaxWarmUpMinutes, &out.MaxWarmUpMinutes
+		*out = new(int)
+		**out = **in
+	}
+	if in.AllowedNodepools != nil {
+		in, out := &in.AllowedNodepools, &out.AllowedNodepools
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxConcurrentWarmPods != nil {
+		in, out := &in.MaxConcurrentWarmPods, &out.MaxConcurrentWarmPods
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AllowedModes != nil {
+		in, out := &in.AllowedModes, &out.AllowedModes
+		*out = make([]PreScaleMode, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreScalePolicySpec.
+func (in *PreScalePolicySpec) DeepCopy() *PreScalePolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PreScalePolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PreScaledCronJob) DeepCopyInto(out *PreScaledCronJob) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreScaledCronJob.
@@ -35,6 +264,22 @@ func (in *PreScaledCronJob) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreScaledCronJobCondition) DeepCopyInto(out *PreScaledCronJobCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreScaledCronJobCondition.
+func (in *PreScaledCronJobCondition) DeepCopy() *PreScaledCronJobCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(PreScaledCronJobCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PreScaledCronJobList) DeepCopyInto(out *PreScaledCronJobList) {
 	*out = *in
@@ -70,7 +315,44 @@ func (in *PreScaledCronJobList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PreScaledCronJobSpec) DeepCopyInto(out *PreScaledCronJobSpec) {
 	*out = *in
+	if in.WarmUpDuration != nil {
+		in, out := &in.WarmUpDuration, &out.WarmUpDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	in.CronJob.DeepCopyInto(&out.CronJob)
+	if in.WarmUpContainer != nil {
+		in, out := &in.WarmUpContainer, &out.WarmUpContainer
+		*out = new(WarmUpContainerTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BarrierTimeoutSeconds != nil {
+		in, out := &in.BarrierTimeoutSeconds, &out.BarrierTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxLateness != nil {
+		in, out := &in.MaxLateness, &out.MaxLateness
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.PrimerSpread != nil {
+		in, out := &in.PrimerSpread, &out.PrimerSpread
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ExclusionCalendars != nil {
+		in, out := &in.ExclusionCalendars, &out.ExclusionCalendars
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Schedules != nil {
+		in, out := &in.Schedules, &out.Schedules
+		*out = make([]ScheduleEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreScaledCronJobSpec.
@@ -86,6 +368,39 @@ func (in *PreScaledCronJobSpec) DeepCopy() *PreScaledCronJobSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PreScaledCronJobStatus) DeepCopyInto(out *PreScaledCronJobStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]PreScaledCronJobCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(DryRunStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastErrorTime != nil {
+		in, out := &in.LastErrorTime, &out.LastErrorTime
+		*out = (*in).DeepCopy()
+	}
+	if in.PrimerOffset != nil {
+		in, out := &in.PrimerOffset, &out.PrimerOffset
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.UpcomingSkips != nil {
+		in, out := &in.UpcomingSkips, &out.UpcomingSkips
+		*out = make([]SkippedRun, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastRun != nil {
+		in, out := &in.LastRun, &out.LastRun
+		*out = new(RunOutcome)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreScaledCronJobStatus.
@@ -97,3 +412,94 @@ func (in *PreScaledCronJobStatus) DeepCopy() *PreScaledCronJobStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunOutcome) DeepCopyInto(out *RunOutcome) {
+	*out = *in
+	if in.WorkloadTime != nil {
+		in, out := &in.WorkloadTime, &out.WorkloadTime
+		*out = (*in).DeepCopy()
+	}
+	in.FinishTime.DeepCopyInto(&out.FinishTime)
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.WarmUpPercent != nil {
+		in, out := &in.WarmUpPercent, &out.WarmUpPercent
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunOutcome.
+func (in *RunOutcome) DeepCopy() *RunOutcome {
+	if in == nil {
+		return nil
+	}
+	out := new(RunOutcome)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduleEntry) DeepCopyInto(out *ScheduleEntry) {
+	*out = *in
+	if in.WarmUpDuration != nil {
+		in, out := &in.WarmUpDuration, &out.WarmUpDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleEntry.
+func (in *ScheduleEntry) DeepCopy() *ScheduleEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SkippedRun) DeepCopyInto(out *SkippedRun) {
+	*out = *in
+	in.WorkloadTime.DeepCopyInto(&out.WorkloadTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SkippedRun.
+func (in *SkippedRun) DeepCopy() *SkippedRun {
+	if in == nil {
+		return nil
+	}
+	out := new(SkippedRun)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WarmUpContainerTemplate) DeepCopyInto(out *WarmUpContainerTemplate) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(corev1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WarmUpContainerTemplate.
+func (in *WarmUpContainerTemplate) DeepCopy() *WarmUpContainerTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(WarmUpContainerTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
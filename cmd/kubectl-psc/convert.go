@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"cronprimer.local/controllers"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"sigs.k8s.io/yaml"
+)
+
+// convert turns a CronJob manifest into a PreScaledCronJob manifest
+func convert(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	filename := flags.String("f", "-", "CronJob manifest to convert, - reads from stdin")
+	warmup := flags.Int("warmup", 0, "Warm-up time in minutes")
+	primer := flags.String("primer", "", "Explicit primer schedule, overrides --warmup")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var data []byte
+	var err error
+	if *filename == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(*filename)
+	}
+	if err != nil {
+		return err
+	}
+
+	cronJob := &batchv1beta1.CronJob{}
+	if err := yaml.UnmarshalStrict(data, cronJob); err != nil {
+		return fmt.Errorf("unable to parse CronJob: %s", err)
+	}
+	if cronJob.Kind != "CronJob" {
+		return fmt.Errorf("expected a CronJob but got %q", cronJob.Kind)
+	}
+
+	instance, err := controllers.ConvertCronJob(cronJob, *warmup, *primer)
+	if err != nil {
+		return err
+	}
+
+	converted, err := yaml.Marshal(instance)
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(converted)
+	return err
+}
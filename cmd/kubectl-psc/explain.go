@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"cronprimer.local/controllers"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// explain shows the schedules of a PreScaledCronJob in the cluster and when they will next fire
+func explain(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("explain", flag.ExitOnError)
+	namespace := addNamespaceFlag(flags)
+	count := flags.Int("count", 5, "Number of upcoming fire times to show")
+	name, err := parseWithName(flags, args)
+	if err != nil {
+		return err
+	}
+
+	clients, err := newClusterClients(*namespace)
+	if err != nil {
+		return err
+	}
+
+	instance := &pscv1alpha1.PreScaledCronJob{}
+	if err := clients.client.Get(context.Background(), types.NamespacedName{Name: name, Namespace: clients.namespace}, instance); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Name:             %s\n", instance.Name)
+	fmt.Fprintf(out, "Namespace:        %s\n", instance.Namespace)
+	for _, view := range controllers.ScheduleInstances(instance) {
+		primerSchedule, err := controllers.PrimerScheduleFor(view)
+		if err != nil {
+			return err
+		}
+
+		if name := controllers.ScheduleName(view); name != "" {
+			fmt.Fprintf(out, "\nSchedule name:    %s\n", name)
+		}
+		fmt.Fprintf(out, "Warm-up:          %s\n", controllers.WarmUpFor(view))
+		if err := printSchedules(out, view.Spec.CronJob.Spec.Schedule, primerSchedule, *count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// simulate runs primer generation offline for a schedule
+func simulate(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	schedule := flags.String("schedule", "", "Cron schedule of the workload")
+	warmup := flags.Int("warmup", 0, "Warm-up time in minutes")
+	primer := flags.String("primer", "", "Explicit primer schedule, overrides --warmup")
+	count := flags.Int("count", 5, "Number of upcoming fire times to show")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *schedule == "" {
+		return fmt.Errorf("--schedule is required")
+	}
+
+	primerSchedule, err := controllers.GetPrimerSchedule(*schedule, *warmup, *primer)
+	if err != nil {
+		return err
+	}
+
+	return printSchedules(out, *schedule, primerSchedule, *count)
+}
+
+func printSchedules(out io.Writer, schedule string, primerSchedule string, count int) error {
+	now := time.Now()
+	scheduleTimes, err := controllers.NextFireTimes(schedule, now, count)
+	if err != nil {
+		return err
+	}
+	primerTimes, err := controllers.NextFireTimes(primerSchedule, now, count)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Schedule:         %s\n", schedule)
+	fmt.Fprintf(out, "Primer schedule:  %s\n", primerSchedule)
+	fmt.Fprintf(out, "\nNext primer runs:\n")
+	for _, t := range primerTimes {
+		fmt.Fprintf(out, "  %s\n", t.Format(time.RFC3339))
+	}
+	fmt.Fprintf(out, "\nNext workload runs:\n")
+	for _, t := range scheduleTimes {
+		fmt.Fprintf(out, "  %s\n", t.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// parseWithName parses flags either side of a single positional name argument
+func parseWithName(flags *flag.FlagSet, args []string) (string, error) {
+	name := ""
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		name, args = args[0], args[1:]
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+
+	if name == "" && flags.NArg() > 0 {
+		name = flags.Arg(0)
+	}
+	if name == "" {
+		return "", fmt.Errorf("a PreScaledCronJob name is required")
+	}
+
+	return name, nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"cronprimer.local/controllers"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// manifestResult is the lint outcome for one CronJob or PreScaledCronJob found in the manifests
+type manifestResult struct {
+	Source    string `json:"source"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	controllers.LintResult
+}
+
+// lint reports which CronJobs and PreScaledCronJobs in a set of manifests can be prescaled
+func lint(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	warmup := flags.Int("warmup", 10, "Warm-up time in minutes assumed for plain CronJobs")
+	output := flags.String("o", "text", "Output format: text, json or junit")
+	failOnRisky := flags.Bool("fail-on-risky", false, "Exit non-zero when risky schedules are found as well as unsupported ones")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	results := []manifestResult{}
+	for _, path := range paths {
+		pathResults, err := lintPath(path, *warmup)
+		if err != nil {
+			return err
+		}
+		results = append(results, pathResults...)
+	}
+
+	var err error
+	switch *output {
+	case "text":
+		err = writeLintText(out, results)
+	case "json":
+		err = writeLintJSON(out, results)
+	case "junit":
+		err = writeLintJUnit(out, results)
+	default:
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if result.Status == controllers.LintUnsupported || (*failOnRisky && result.Status == controllers.LintRisky) {
+			return fmt.Errorf("found schedules which can't be prescaled")
+		}
+	}
+
+	return nil
+}
+
+// lintPath lints a manifest file, every yaml file under a directory, or stdin for "-"
+func lintPath(path string, warmup int) ([]manifestResult, error) {
+	if path == "-" {
+		return lintStream("stdin", os.Stdin, warmup)
+	}
+
+	results := []manifestResult{}
+	err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if ext := filepath.Ext(file); ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			return nil
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		fileResults, err := lintStream(file, f, warmup)
+		if err != nil {
+			return err
+		}
+		results = append(results, fileResults...)
+		return nil
+	})
+
+	return results, err
+}
+
+// lintStream lints every document in a multi-document yaml stream
+func lintStream(source string, r io.Reader, warmup int) ([]manifestResult, error) {
+	results := []manifestResult{}
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return results, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", source, err)
+		}
+
+		typeMeta := metav1.TypeMeta{}
+		if err := yaml.Unmarshal(doc, &typeMeta); err != nil {
+			return nil, fmt.Errorf("%s: %s", source, err)
+		}
+
+		switch typeMeta.Kind {
+		case "CronJob":
+			cronJob := &batchv1beta1.CronJob{}
+			if err := yaml.Unmarshal(doc, cronJob); err != nil {
+				return nil, fmt.Errorf("%s: %s", source, err)
+			}
+			results = append(results, manifestResult{
+				Source:     source,
+				Kind:       typeMeta.Kind,
+				Namespace:  cronJob.Namespace,
+				Name:       cronJob.Name,
+				LintResult: controllers.LintPrimerSchedule(cronJob.Spec.Schedule, warmup, ""),
+			})
+		case "PreScaledCronJob":
+			instance := &pscv1alpha1.PreScaledCronJob{}
+			if err := yaml.Unmarshal(doc, instance); err != nil {
+				return nil, fmt.Errorf("%s: %s", source, err)
+			}
+			// each schedule is primed on its own, so is linted on its own
+			for _, view := range controllers.ScheduleInstances(instance) {
+				name := instance.Name
+				if scheduleName := controllers.ScheduleName(view); scheduleName != "" {
+					name += "/" + scheduleName
+				}
+				results = append(results, manifestResult{
+					Source:     source,
+					Kind:       typeMeta.Kind,
+					Namespace:  instance.Namespace,
+					Name:       name,
+					LintResult: controllers.LintPrimerSchedule(view.Spec.CronJob.Spec.Schedule, controllers.WarmUpMinutes(controllers.WarmUpFor(view)), view.Spec.PrimerSchedule),
+				})
+			}
+		}
+	}
+}
+
+func writeLintText(out io.Writer, results []manifestResult) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tKIND\tNAMESPACE\tNAME\tSTATUS\tPRIMER SCHEDULE\tREASON")
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", result.Source, result.Kind, result.Namespace, result.Name, result.Status, result.PrimerSchedule, result.Reason)
+	}
+	return w.Flush()
+}
+
+func writeLintJSON(out io.Writer, results []manifestResult) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+type junitTestSuite struct {
+	XMLName  xml.Name        `xml:"testsuite"`
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeLintJUnit reports unsupported schedules as failures and risky schedules as skipped tests
+func writeLintJUnit(out io.Writer, results []manifestResult) error {
+	suite := junitTestSuite{Name: "prescaledcronjob-lint", Tests: len(results)}
+	for _, result := range results {
+		testCase := junitTestCase{
+			Name:      strings.TrimPrefix(result.Namespace+"/"+result.Name, "/"),
+			ClassName: result.Source + "." + result.Kind,
+		}
+
+		switch result.Status {
+		case controllers.LintUnsupported:
+			testCase.Failure = &junitMessage{Message: result.Reason}
+			suite.Failures++
+		case controllers.LintRisky:
+			testCase.Skipped = &junitMessage{Message: result.Reason}
+			suite.Skipped++
+		}
+
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const usage = `kubectl psc inspects and simulates PreScaledCronJobs
+
+Usage:
+  kubectl psc explain <name> [-n namespace] [--count N]
+  kubectl psc simulate --schedule <cron> (--warmup <mins> | --primer <cron>) [--count N]
+  kubectl psc runs <name> [-n namespace]
+  kubectl psc convert -f <cronjob.yaml> (--warmup <mins> | --primer <cron>)
+  kubectl psc lint [--warmup <mins>] [-o text|json|junit] [--fail-on-risky] [<file or directory>...]
+`
+
+type command func(args []string, out io.Writer) error
+
+var commands = map[string]command{
+	"explain":  explain,
+	"simulate": simulate,
+	"runs":     runs,
+	"convert":  convert,
+	"lint":     lint,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cmd, exists := commands[os.Args[1]]
+	if !exists {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err := cmd(os.Args[2:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// clusterClients holds everything the cluster facing commands need
+type clusterClients struct {
+	client    client.Client
+	clientset *kubernetes.Clientset
+	namespace string
+}
+
+// addNamespaceFlag registers -n/--namespace on a command's flags
+func addNamespaceFlag(flags *flag.FlagSet) *string {
+	namespace := flags.String("namespace", "", "Namespace of the PreScaledCronJob, defaults to the current kubeconfig context")
+	flags.StringVar(namespace, "n", "", "Shorthand for --namespace")
+	return namespace
+}
+
+// newClusterClients builds clients from the same kubeconfig resolution kubectl uses
+func newClusterClients(namespace string) (*clusterClients, error) {
+	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{},
+	)
+
+	cfg, err := kubeConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load kubeconfig: %s", err)
+	}
+
+	if namespace == "" {
+		if namespace, _, err = kubeConfig.Namespace(); err != nil {
+			return nil, fmt.Errorf("unable to determine namespace: %s", err)
+		}
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := pscv1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clusterClients{client: c, clientset: clientset, namespace: namespace}, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"cronprimer.local/controllers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// runs lists the recent primed pods of a PreScaledCronJob with their transition timings
+func runs(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("runs", flag.ExitOnError)
+	namespace := addNamespaceFlag(flags)
+	name, err := parseWithName(flags, args)
+	if err != nil {
+		return err
+	}
+
+	clients, err := newClusterClients(*namespace)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	instance := &pscv1alpha1.PreScaledCronJob{}
+	if err := clients.client.Get(ctx, types.NamespacedName{Name: name, Namespace: clients.namespace}, instance); err != nil {
+		return err
+	}
+
+	pods := &corev1.PodList{}
+	if err := clients.client.List(ctx, pods, client.InNamespace(clients.namespace), client.MatchingLabels{controllers.PrimedCronLabel: name}); err != nil {
+		return err
+	}
+
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].CreationTimestamp.After(pods.Items[j].CreationTimestamp.Time)
+	})
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprint(w, "POD\tCREATED\tPHASE")
+	for _, transition := range controllers.TransitionNames {
+		fmt.Fprintf(w, "\t%s", transition)
+	}
+	fmt.Fprintln(w)
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		events, err := clients.clientset.CoreV1().Events(pod.Namespace).List(metav1.ListOptions{
+			FieldSelector: fields.AndSelectors(fields.OneTermEqualSelector("involvedObject.name", pod.Name), fields.OneTermEqualSelector("involvedObject.namespace", pod.Namespace)).String(),
+		})
+		if err != nil {
+			return err
+		}
+
+		// the delay is measured against the workload time the operator stamped the pod with, pods it never
+		// stamped have it worked out from their job where their schedule still exists
+		view, err := controllers.ScheduleInstanceForPod(instance, pod)
+		if err != nil {
+			view = instance
+		}
+		workloadAt, _ := controllers.PodWorkloadTime(pod, view)
+
+		// partial failures still leave the other transitions worth showing
+		transitions, _ := controllers.ObservedTransitions(events.Items, pod, workloadAt)
+
+		fmt.Fprintf(w, "%s\t%s\t%s", pod.Name, pod.CreationTimestamp.Format(time.RFC3339), pod.Status.Phase)
+		for _, transition := range controllers.TransitionNames {
+			duration, observed := transitions[transition]
+			if observed {
+				fmt.Fprintf(w, "\t%s", duration)
+			} else {
+				fmt.Fprint(w, "\t-")
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	return w.Flush()
+}
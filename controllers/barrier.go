@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// BarrierAnnotation is set to released on every pod of a job once the operator lets them start together
+	BarrierAnnotation = "psc.cronprimer.local/barrier"
+	barrierReleased   = "released"
+	barrierFile       = "barrier"
+
+	defaultBarrierTimeout = time.Minute * 5
+
+	// barrierAllScheduled is the outcome when every pod of the job was scheduled before the deadline
+	barrierAllScheduled = "allscheduled"
+	// barrierDeadlinePassed is the outcome when the pods were released at the deadline without the rest of the job
+	barrierDeadlinePassed = "deadline"
+)
+
+// barrierTimeout is how long past the workload time pods are held waiting for the rest of their job
+func barrierTimeout(instance *pscv1alpha1.PreScaledCronJob) time.Duration {
+	if instance.Spec.BarrierTimeoutSeconds == nil {
+		return defaultBarrierTimeout
+	}
+	return time.Duration(*instance.Spec.BarrierTimeoutSeconds) * time.Second
+}
+
+// expectedJobPods is how many pods of a job run at once and so have to be scheduled before the barrier is released
+func expectedJobPods(instance *pscv1alpha1.PreScaledCronJob) int64 {
+	pods := podsPerRun(instance)
+	completions := instance.Spec.CronJob.Spec.JobTemplate.Spec.Completions
+	if completions != nil && int64(*completions) < pods && *completions > 0 {
+		return int64(*completions)
+	}
+	return pods
+}
+
+// barrierDecision decides whether the pods of a job can be released, and if not how long until the deadline
+func barrierDecision(pods []corev1.Pod, expected int64, workloadAt time.Time, timeout time.Duration, now time.Time) (release bool, outcome string, wait time.Duration) {
+	var scheduled int64
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" {
+			scheduled++
+		}
+	}
+
+	if scheduled >= expected {
+		return true, barrierAllScheduled, 0
+	}
+
+	deadline := workloadAt.Add(timeout)
+	if !now.Before(deadline) {
+		return true, barrierDeadlinePassed, 0
+	}
+	return false, "", deadline.Sub(now)
+}
+
+// heldAtBarrier reports whether a pod of a prescaledcronjob in barrier mode is still waiting to be released
+func heldAtBarrier(pod *corev1.Pod, instance *pscv1alpha1.PreScaledCronJob) bool {
+	return instance.Spec.ReleaseMode == pscv1alpha1.BarrierRelease && pod.Annotations[BarrierAnnotation] != barrierReleased
+}
+
+// reconcileBarrier releases the pods of a job together once all of them have been scheduled, or at the deadline
+func (r *PodReconciler) reconcileBarrier(ctx context.Context, pod *corev1.Pod, instance *pscv1alpha1.PreScaledCronJob, workloadAt time.Time) (ctrl.Result, error) {
+	jobName := jobNameFor(pod)
+	list := &corev1.PodList{}
+	if err := r.Pods.List(ctx, list, client.InNamespace(pod.Namespace), client.MatchingLabels{jobNameLabel: jobName}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	pods := []corev1.Pod{}
+	alreadyReleased := false
+	for _, item := range list.Items {
+		if item.DeletionTimestamp != nil || item.Status.Phase == corev1.PodSucceeded || item.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if item.Annotations[BarrierAnnotation] == barrierReleased {
+			alreadyReleased = true
+		}
+		pods = append(pods, item)
+	}
+
+	now := time.Now()
+	if !alreadyReleased {
+		release, outcome, wait := barrierDecision(pods, expectedJobPods(instance), workloadAt, barrierTimeout(instance), now)
+		if !release {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+
+		delay := now.Sub(workloadAt)
+		if delay < 0 {
+			delay = 0
+		}
+		TrackBarrierRelease(instance.Name, outcome, delay)
+		recordEvent(ctx, r.Recorder, instance, corev1.EventTypeNormal, "BarrierReleased",
+			fmt.Sprintf("Released %d pods of job %s (%s), %s after the workload time", len(pods), jobName, outcome, delay.Round(time.Second)))
+	}
+
+	// pods recreated after the release are let through straight away
+	for i := range pods {
+		if pods[i].Annotations[BarrierAnnotation] == barrierReleased {
+			continue
+		}
+		patch := client.MergeFrom(pods[i].DeepCopy())
+		if pods[i].Annotations == nil {
+			pods[i].Annotations = map[string]string{}
+		}
+		pods[i].Annotations[BarrierAnnotation] = barrierReleased
+		if err := r.Patch(ctx, &pods[i], patch); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
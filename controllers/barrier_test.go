@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestBarrierDecision(t *testing.T) {
+	workloadAt := time.Date(2020, 1, 29, 12, 30, 0, 0, time.UTC)
+	scheduled := corev1.Pod{Spec: corev1.PodSpec{NodeName: "node-1"}}
+	pending := corev1.Pod{}
+
+	scenarios := []struct {
+		name    string
+		pods    []corev1.Pod
+		now     time.Time
+		release bool
+		outcome string
+		wait    time.Duration
+	}{
+		{"all scheduled early", []corev1.Pod{scheduled, scheduled}, workloadAt.Add(-time.Minute), true, barrierAllScheduled, 0},
+		{"one pending before deadline", []corev1.Pod{scheduled, pending}, workloadAt.Add(time.Minute), false, "", time.Minute * 4},
+		{"one pod not created yet", []corev1.Pod{scheduled}, workloadAt, false, "", time.Minute * 5},
+		{"deadline passed", []corev1.Pod{scheduled, pending}, workloadAt.Add(time.Minute * 5), true, barrierDeadlinePassed, 0},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			release, outcome, wait := barrierDecision(scenario.pods, 2, workloadAt, defaultBarrierTimeout, scenario.now)
+			require.Equal(t, scenario.release, release)
+			require.Equal(t, scenario.outcome, outcome)
+			require.Equal(t, scenario.wait, wait)
+		})
+	}
+}
+
+func TestReconcileBarrier_ReleasesEveryPodOfTheJob(t *testing.T) {
+	parallelism := int32(2)
+	instance := newPreviewPSC("hourly", "30 * * * *", 10, "")
+	instance.Spec.ReleaseMode = pscv1alpha1.BarrierRelease
+	instance.Spec.CronJob.Spec.JobTemplate.Spec.Parallelism = &parallelism
+
+	first := newPrimedPod("autogen-hourly-1580098800", "autogen-hourly-1580098800-abcde")
+	second := newPrimedPod("autogen-hourly-1580098800", "autogen-hourly-1580098800-fghij")
+	other := newPrimedPod("autogen-hourly-1580102400", "autogen-hourly-1580102400-klmno")
+	for _, pod := range []*corev1.Pod{first, second, other} {
+		pod.Spec.NodeName = "node-1"
+	}
+
+	podCache := NewPrimedPodCache(fake.NewSimpleClientset(first, second, other), nil, 0)
+	stop := make(chan struct{})
+	defer close(stop)
+	for _, factory := range podCache.factories {
+		factory.Start(stop)
+		factory.WaitForCacheSync(stop)
+	}
+
+	r := newTestPodReconciler(t, first, second, other)
+	r.Pods = podCache
+	ctx := context.Background()
+
+	result, err := r.reconcileBarrier(ctx, first, &instance, time.Now())
+	require.NoError(t, err)
+	require.Zero(t, result.RequeueAfter)
+
+	for _, pod := range []*corev1.Pod{first, second, other} {
+		fetched := &corev1.Pod{}
+		require.NoError(t, r.Get(ctx, types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, fetched))
+		if pod == other {
+			require.Empty(t, fetched.Annotations[BarrierAnnotation])
+		} else {
+			require.Equal(t, barrierReleased, fetched.Annotations[BarrierAnnotation])
+		}
+	}
+}
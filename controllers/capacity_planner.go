@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/ReneKroon/ttlcache"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultPlanInterval = time.Minute
+	defaultPlanHorizon  = time.Hour
+	defaultPlanWindow   = time.Minute * 5
+)
+
+var reportedDemand = ttlcache.NewCache()
+
+// CapacityPlanner periodically combines the upcoming warm-ups of every prescaledcronjob by nodepool and
+// time window, so the overall demand on a nodepool can be seen rather than one warm pod at a time
+type CapacityPlanner struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+
+	// Interval is how often the plan is refreshed
+	Interval time.Duration
+	// Horizon is how far ahead of now runs are planned
+	Horizon time.Duration
+	// Window groups runs whose workload times fall into the same window of this size
+	Window time.Duration
+}
+
+// CapacityDemand is the combined demand of all runs targeting a nodepool within one window
+type CapacityDemand struct {
+	Nodepool          string
+	WindowStart       time.Time
+	WarmFrom          time.Time
+	Pods              int64
+	CPU               resource.Quantity
+	Memory            resource.Quantity
+	PreScaledCronJobs []types.NamespacedName
+}
+
+// String gives a human readable summary of the demand
+func (d CapacityDemand) String() string {
+	return fmt.Sprintf("nodepool %s needs ~%s CPUs and %s memory for %d pods at %s, warming from %s (%d prescaledcronjobs)",
+		d.Nodepool, d.CPU.String(), d.Memory.String(), d.Pods, d.WindowStart.Format("15:04"), d.WarmFrom.Format("15:04"), len(d.PreScaledCronJobs))
+}
+
+// Start runs the planner until stop is closed
+func (p *CapacityPlanner) Start(stop <-chan struct{}) error {
+	interval := p.Interval
+	if interval == 0 {
+		interval = defaultPlanInterval
+	}
+
+	wait.Until(p.plan, interval, stop)
+	return nil
+}
+
+func (p *CapacityPlanner) plan() {
+	horizon := p.Horizon
+	if horizon == 0 {
+		horizon = defaultPlanHorizon
+	}
+	window := p.Window
+	if window == 0 {
+		window = defaultPlanWindow
+	}
+
+	list := &pscv1alpha1.PreScaledCronJobList{}
+	if err := p.List(context.Background(), list); err != nil {
+		p.Log.Error(err, "Failed to list prescaledcronjobs")
+		return
+	}
+
+	now := time.Now()
+	demands, err := PlanCapacity(list.Items, now, now.Add(horizon), window)
+	if err != nil {
+		// invalid schedules are already reported by the prescaledcronjob reconciler
+		p.Log.V(debugLevel).Info("Some prescaledcronjobs were left out of the capacity plan", "reason", err.Error())
+	}
+
+	TrackPlannedCapacity(demands)
+
+	instances := map[types.NamespacedName]*pscv1alpha1.PreScaledCronJob{}
+	for i := range list.Items {
+		instance := &list.Items[i]
+		instances[types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}] = instance
+	}
+
+	for _, demand := range demands {
+		// a single prescaledcronjob warming up on its own isn't interesting
+		if len(demand.PreScaledCronJobs) < 2 {
+			continue
+		}
+
+		// only report each overlap once, keeping it long enough for the window to have passed
+		key := demand.Nodepool + "/" + demand.WindowStart.Format(time.RFC3339)
+		if _, reported := reportedDemand.Get(key); reported {
+			continue
+		}
+		reportedDemand.SetWithTTL(key, true, horizon+window)
+
+		p.Log.Info("Overlapping warm-ups planned", "nodepool", demand.Nodepool, "window", demand.WindowStart, "demand", demand.String())
+		for _, name := range demand.PreScaledCronJobs {
+			if instance, exists := instances[name]; exists {
+				p.Recorder.Event(instance, corev1.EventTypeNormal, "CapacityPlanned", demand.String())
+			}
+		}
+	}
+}
+
+// PlanCapacity groups the primed runs due between from and until by nodepool and workload window, summing the
+// resource requests of the pods each run will create. Objects whose schedules can't be parsed are left out and
+// reported through the returned error.
+func PlanCapacity(instances []pscv1alpha1.PreScaledCronJob, from time.Time, until time.Time, window time.Duration) ([]CapacityDemand, error) {
+	var lastErr error
+	byKey := map[string]*CapacityDemand{}
+
+	for i := range instances {
+		instance := &instances[i]
+		runs, err := UpcomingRuns(instance, from, until)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		podSpec := &instance.Spec.CronJob.Spec.JobTemplate.Spec.Template.Spec
+		nodepool := nodepoolFor(podSpec)
+		pods := podsPerRun(instance)
+		requests := podRequests(podSpec)
+		name := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+
+		for _, run := range runs {
+			windowStart := run.WorkloadAt.Truncate(window)
+			key := nodepool + "/" + windowStart.Format(time.RFC3339)
+
+			demand, exists := byKey[key]
+			if !exists {
+				demand = &CapacityDemand{Nodepool: nodepool, WindowStart: windowStart, WarmFrom: run.PrimerAt}
+				byKey[key] = demand
+			}
+
+			if run.PrimerAt.Before(demand.WarmFrom) {
+				demand.WarmFrom = run.PrimerAt
+			}
+			demand.Pods += pods
+			for i := int64(0); i < pods; i++ {
+				demand.CPU.Add(*requests.Cpu())
+				demand.Memory.Add(*requests.Memory())
+			}
+			if !containsName(demand.PreScaledCronJobs, name) {
+				demand.PreScaledCronJobs = append(demand.PreScaledCronJobs, name)
+			}
+		}
+	}
+
+	demands := []CapacityDemand{}
+	for _, demand := range byKey {
+		demands = append(demands, *demand)
+	}
+	sort.Slice(demands, func(i, j int) bool {
+		if demands[i].WindowStart.Equal(demands[j].WindowStart) {
+			return demands[i].Nodepool < demands[j].Nodepool
+		}
+		return demands[i].WindowStart.Before(demands[j].WindowStart)
+	})
+
+	return demands, lastErr
+}
+
+// podsPerRun is how many pods a job started by the prescaledcronjob runs at once
+func podsPerRun(instance *pscv1alpha1.PreScaledCronJob) int64 {
+	parallelism := instance.Spec.CronJob.Spec.JobTemplate.Spec.Parallelism
+	if parallelism == nil || *parallelism < 1 {
+		return 1
+	}
+	return int64(*parallelism)
+}
+
+// podRequests works out the effective requests of a pod the same way the scheduler does, the larger of the
+// sum of its containers and the largest of its init containers
+func podRequests(podSpec *corev1.PodSpec) corev1.ResourceList {
+	requests := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.Quantity{},
+		corev1.ResourceMemory: resource.Quantity{},
+	}
+
+	for name := range requests {
+		total := resource.Quantity{}
+		for _, container := range podSpec.Containers {
+			if request, exists := container.Resources.Requests[name]; exists {
+				total.Add(request)
+			}
+		}
+		for _, container := range podSpec.InitContainers {
+			if request, exists := container.Resources.Requests[name]; exists && request.Cmp(total) > 0 {
+				total = request.DeepCopy()
+			}
+		}
+		requests[name] = total
+	}
+
+	return requests
+}
+
+func containsName(names []types.NamespacedName, name types.NamespacedName) bool {
+	for _, item := range names {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func newPlannedPSC(name string, schedule string, warmupMinutes int, nodepool string, cpu string, memory string) pscv1alpha1.PreScaledCronJob {
+	instance := newPreviewPSC(name, schedule, warmupMinutes, nodepool)
+	instance.Spec.CronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Resources.Requests = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(memory),
+	}
+	return instance
+}
+
+func TestPlanCapacity_CombinesRunsInTheSameWindow(t *testing.T) {
+	parallelism := int32(2)
+	parallel := newPlannedPSC("parallel", "0 0 * * *", 10, "gpu", "4", "8Gi")
+	parallel.Spec.CronJob.Spec.JobTemplate.Spec.Parallelism = &parallelism
+
+	instances := []pscv1alpha1.PreScaledCronJob{
+		newPlannedPSC("early", "0 0 * * *", 15, "gpu", "2", "1Gi"),
+		newPlannedPSC("late", "2 0 * * *", 5, "gpu", "2", "1Gi"),
+		parallel,
+		newPlannedPSC("elsewhere", "0 0 * * *", 5, "cpu", "1", "1Gi"),
+		newPlannedPSC("later", "30 0 * * *", 5, "gpu", "1", "1Gi"),
+	}
+
+	from := time.Date(2020, 1, 29, 23, 30, 0, 0, time.UTC)
+	demands, err := PlanCapacity(instances, from, from.Add(time.Hour), time.Minute*5)
+	require.NoError(t, err)
+	require.Len(t, demands, 3)
+
+	midnight := time.Date(2020, 1, 30, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, "cpu", demands[0].Nodepool)
+	require.Equal(t, midnight, demands[0].WindowStart)
+
+	gpu := demands[1]
+	require.Equal(t, "gpu", gpu.Nodepool)
+	require.Equal(t, midnight, gpu.WindowStart)
+	require.Equal(t, time.Date(2020, 1, 29, 23, 45, 0, 0, time.UTC), gpu.WarmFrom)
+	require.Equal(t, int64(4), gpu.Pods)
+	require.Equal(t, "12", gpu.CPU.String())
+	require.Equal(t, "18Gi", gpu.Memory.String())
+	require.Len(t, gpu.PreScaledCronJobs, 3)
+
+	require.Equal(t, "gpu", demands[2].Nodepool)
+	require.Equal(t, midnight.Add(time.Minute*30), demands[2].WindowStart)
+	require.Len(t, demands[2].PreScaledCronJobs, 1)
+}
+
+func TestPodRequests_UsesLargestInitContainer(t *testing.T) {
+	podSpec := &corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}}},
+			{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}}},
+		},
+		InitContainers: []corev1.Container{
+			{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("2"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			}}},
+		},
+	}
+
+	requests := podRequests(podSpec)
+
+	require.Equal(t, "2", requests.Cpu().String())
+	require.Equal(t, "1Gi", requests.Memory().String())
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// setCondition adds or updates a condition on the status, returning whether anything changed
+func setCondition(status *pscv1alpha1.PreScaledCronJobStatus, conditionType pscv1alpha1.PreScaledCronJobConditionType,
+	conditionStatus corev1.ConditionStatus, reason string, message string) bool {
+
+	existing := findCondition(status, conditionType)
+	if existing == nil {
+		status.Conditions = append(status.Conditions, pscv1alpha1.PreScaledCronJobCondition{
+			Type:               conditionType,
+			Status:             conditionStatus,
+			LastTransitionTime: metav1.Now(),
+			Reason:             reason,
+			Message:            message,
+		})
+		return true
+	}
+
+	if existing.Status == conditionStatus && existing.Reason == reason && existing.Message == message {
+		return false
+	}
+
+	if existing.Status != conditionStatus {
+		existing.LastTransitionTime = metav1.Now()
+	}
+	existing.Status = conditionStatus
+	existing.Reason = reason
+	existing.Message = message
+	return true
+}
+
+// findCondition returns the condition of the given type, or nil if it isn't set
+func findCondition(status *pscv1alpha1.PreScaledCronJobStatus, conditionType pscv1alpha1.PreScaledCronJobConditionType) *pscv1alpha1.PreScaledCronJobCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == conditionType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
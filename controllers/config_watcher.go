@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"time"
+
+	configv1alpha1 "cronprimer.local/api/config/v1alpha1"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const defaultConfigCheckInterval = time.Second * 10
+
+// ConfigWatcher polls the configuration file and applies changes to the tunables which are safe to change
+// while running. Other changes are logged as needing a restart, and invalid files are ignored.
+type ConfigWatcher struct {
+	Path string
+	Log  logr.Logger
+
+	// Current is the effective configuration the manager was started with
+	Current *configv1alpha1.OperatorConfig
+	// Overrides reapplies flag and environment overrides so they keep precedence over the file
+	Overrides func(*configv1alpha1.OperatorConfig)
+	// Interval is how often the file is checked for changes
+	Interval time.Duration
+
+	lastData []byte
+}
+
+// TunablesFromConfig picks the tunables out of the operator configuration
+func TunablesFromConfig(c *configv1alpha1.OperatorConfig) Tunables {
+	return Tunables{
+		NodepoolLabel:    c.NodepoolLabel,
+		EventTrackingTTL: c.EventTrackingTTL.Duration,
+		Cost:             c.Cost,
+	}
+}
+
+// Start checks the file until stop is closed
+func (w *ConfigWatcher) Start(stop <-chan struct{}) error {
+	interval := w.Interval
+	if interval == 0 {
+		interval = defaultConfigCheckInterval
+	}
+
+	wait.Until(w.check, interval, stop)
+	return nil
+}
+
+// NeedLeaderElection is false so every replica picks up changes, not just the leader
+func (w *ConfigWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+func (w *ConfigWatcher) check() {
+	data, err := ioutil.ReadFile(w.Path)
+	if err != nil {
+		w.Log.Error(err, "Failed to read operator config", "path", w.Path)
+		return
+	}
+
+	// mounted configmaps are swapped out whole, so only act when the content changes
+	if w.lastData != nil && bytes.Equal(data, w.lastData) {
+		return
+	}
+	w.lastData = data
+
+	updated, err := configv1alpha1.Decode(data)
+	if err == nil {
+		if w.Overrides != nil {
+			w.Overrides(updated)
+		}
+		err = updated.Validate()
+	}
+	if err != nil {
+		w.Log.Error(err, "Ignoring invalid operator config, keeping the current one", "path", w.Path)
+		return
+	}
+
+	if reflect.DeepEqual(updated, w.Current) {
+		return
+	}
+
+	if restartRequired(w.Current, updated) {
+		w.Log.Info("Operator config changed in ways which need a restart to take effect", "path", w.Path)
+	}
+
+	SetTunables(TunablesFromConfig(updated))
+	w.Log.Info("Reloaded operator config", "nodepoolLabel", updated.NodepoolLabel, "eventTrackingTTL", updated.EventTrackingTTL.Duration.String(), "prices", len(updated.Cost.Prices))
+	w.Current = updated
+}
+
+// restartRequired is true when anything other than the tunables differs between the configs
+func restartRequired(current *configv1alpha1.OperatorConfig, updated *configv1alpha1.OperatorConfig) bool {
+	// copies only have their own fields replaced, so sharing slices is fine
+	before, after := *current, *updated
+	before.NodepoolLabel = after.NodepoolLabel
+	before.EventTrackingTTL = after.EventTrackingTTL
+	before.Cost = after.Cost
+
+	return !reflect.DeepEqual(before, after)
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	configv1alpha1 "cronprimer.local/api/config/v1alpha1"
+	"github.com/stretchr/testify/require"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestConfigWatcher_ReloadsTunablesAndIgnoresInvalidFiles(t *testing.T) {
+	defer SetTunables(currentTunables())
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(data string) {
+		require.NoError(t, ioutil.WriteFile(path, []byte("apiVersion: config.cronprimer.local/v1alpha1\nkind: OperatorConfig\n"+data), 0600))
+	}
+
+	writeConfig("")
+	current, err := configv1alpha1.Load(path)
+	require.NoError(t, err)
+	SetTunables(TunablesFromConfig(current))
+
+	watcher := &ConfigWatcher{
+		Path:    path,
+		Log:     ctrl.Log.WithName("test"),
+		Current: current,
+		Overrides: func(c *configv1alpha1.OperatorConfig) {
+			c.Metrics.BindAddress = "0"
+		},
+	}
+
+	writeConfig("nodepoolLabel: pool\neventTrackingTTL: 2h\n")
+	watcher.check()
+	require.Equal(t, Tunables{NodepoolLabel: "pool", EventTrackingTTL: time.Hour * 2}, currentTunables())
+	require.Equal(t, "0", watcher.Current.Metrics.BindAddress)
+
+	writeConfig("nodepoolLabel: not a label\n")
+	watcher.check()
+	require.Equal(t, "pool", currentTunables().NodepoolLabel)
+}
+
+func TestRestartRequired_OnlyForSettingsWhichCantReload(t *testing.T) {
+	current, err := configv1alpha1.Load("")
+	require.NoError(t, err)
+
+	reloadable := *current
+	reloadable.NodepoolLabel = "pool"
+	reloadable.Cost = configv1alpha1.CostConfig{Currency: "EUR", Prices: []configv1alpha1.NodepoolPrice{{CPUCoreHour: 0.04}}}
+	require.False(t, restartRequired(current, &reloadable))
+
+	restart := *current
+	restart.Webhook.Port = 9444
+	require.True(t, restartRequired(current, &restart))
+}
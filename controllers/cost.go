@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"time"
+
+	configv1alpha1 "cronprimer.local/api/config/v1alpha1"
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// bytesPerGB converts memory requests to the decimal GB prices are quoted in
+const bytesPerGB = 1e9
+
+// WarmUpReservation is what a primed pod's requests held on its node while the warm-up container waited
+type WarmUpReservation struct {
+	CPUCoreSeconds  float64
+	MemoryGBSeconds float64
+}
+
+// Cost is the price of a reservation given hourly prices
+func (r WarmUpReservation) Cost(price configv1alpha1.NodepoolPrice) float64 {
+	secondsPerHour := time.Hour.Seconds()
+	return r.CPUCoreSeconds/secondsPerHour*price.CPUCoreHour + r.MemoryGBSeconds/secondsPerHour*price.MemoryGBHour
+}
+
+// warmUpReservation works out the resources a pod reserved for the time its warm-up container ran, using its
+// effective requests as that is what the scheduler kept free for it
+func warmUpReservation(podSpec *corev1.PodSpec, waited time.Duration) WarmUpReservation {
+	if waited <= 0 {
+		return WarmUpReservation{}
+	}
+
+	requests := podRequests(podSpec)
+	cpu := requests[corev1.ResourceCPU]
+	memory := requests[corev1.ResourceMemory]
+	return WarmUpReservation{
+		CPUCoreSeconds:  float64(cpu.MilliValue()) / 1000 * waited.Seconds(),
+		MemoryGBSeconds: float64(memory.Value()) / bytesPerGB * waited.Seconds(),
+	}
+}
+
+// priceFor finds the price of a nodepool, falling back to the price without a nodepool
+func priceFor(cost configv1alpha1.CostConfig, nodepool string) (configv1alpha1.NodepoolPrice, bool) {
+	var fallback *configv1alpha1.NodepoolPrice
+	for i, price := range cost.Prices {
+		if price.Nodepool == nodepool {
+			return price, true
+		}
+		if price.Nodepool == "" {
+			fallback = &cost.Prices[i]
+		}
+	}
+	if fallback == nil {
+		return configv1alpha1.NodepoolPrice{}, false
+	}
+	return *fallback, true
+}
+
+// trackWarmUpCost publishes the resources a primed pod reserved while warming up, and their price when its
+// nodepool has one
+func trackWarmUpCost(pod *corev1.Pod, prescaledInstance *pscv1alpha1.PreScaledCronJob, waited time.Duration) {
+	nodepool := nodepoolFor(&pod.Spec)
+	reservation := warmUpReservation(&pod.Spec, waited)
+	TrackWarmUpReservation(prescaledInstance.Name, nodepool, reservation)
+
+	cost := currentTunables().Cost
+	if price, priced := priceFor(cost, nodepool); priced {
+		TrackWarmUpCost(prescaledInstance.Name, nodepool, cost.Currency, reservation.Cost(price))
+	}
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	configv1alpha1 "cronprimer.local/api/config/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestWarmUpReservation_UsesTheEffectiveRequests(t *testing.T) {
+	podSpec := &corev1.PodSpec{
+		InitContainers: []corev1.Container{
+			{Name: warmupContainerInjectNameUID, Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("10m"),
+				corev1.ResourceMemory: resource.MustParse("64Mi"),
+			}}},
+		},
+		Containers: []corev1.Container{
+			{Name: "workload", Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("2"),
+				corev1.ResourceMemory: resource.MustParse("4G"),
+			}}},
+		},
+	}
+
+	reservation := warmUpReservation(podSpec, time.Minute*15)
+	require.Equal(t, WarmUpReservation{CPUCoreSeconds: 1800, MemoryGBSeconds: 3600}, reservation)
+	require.InDelta(t, 0.03, reservation.Cost(configv1alpha1.NodepoolPrice{CPUCoreHour: 0.04, MemoryGBHour: 0.01}), 1e-9)
+
+	require.Equal(t, WarmUpReservation{}, warmUpReservation(podSpec, 0))
+}
+
+func TestPriceFor_FallsBackToThePriceWithoutANodepool(t *testing.T) {
+	cost := configv1alpha1.CostConfig{
+		Currency: "EUR",
+		Prices: []configv1alpha1.NodepoolPrice{
+			{CPUCoreHour: 0.04},
+			{Nodepool: "gpu", CPUCoreHour: 0.9},
+		},
+	}
+
+	price, priced := priceFor(cost, "gpu")
+	require.True(t, priced)
+	require.Equal(t, 0.9, price.CPUCoreHour)
+
+	price, priced = priceFor(cost, "agentpool1")
+	require.True(t, priced)
+	require.Equal(t, 0.04, price.CPUCoreHour)
+
+	_, priced = priceFor(configv1alpha1.CostConfig{}, "gpu")
+	require.False(t, priced)
+}
@@ -0,0 +1,436 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronFieldKind is the position of a field in a cron schedule
+type CronFieldKind int
+
+const (
+	// MinuteField is the first field of a cron schedule
+	MinuteField CronFieldKind = iota
+	// HourField is the second field of a cron schedule
+	HourField
+	// DayOfMonthField is the third field of a cron schedule
+	DayOfMonthField
+	// MonthField is the fourth field of a cron schedule
+	MonthField
+	// DayOfWeekField is the fifth field of a cron schedule
+	DayOfWeekField
+)
+
+type cronFieldBounds struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var cronFields = [...]cronFieldBounds{
+	MinuteField:     {name: "minute", min: 0, max: 59},
+	HourField:       {name: "hour", min: 0, max: 23},
+	DayOfMonthField: {name: "day-of-month", min: 1, max: 31},
+	MonthField: {name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	DayOfWeekField: {name: "day-of-week", min: 0, max: 6, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+func (k CronFieldKind) String() string {
+	return cronFields[k].name
+}
+
+// cronDescriptors are the predefined schedules and the fields they stand for
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+const everyDescriptor = "@every "
+
+// CronSchedule is a parsed cron schedule. It accepts everything cron.ParseStandard does, which is also what the
+// Kubernetes CronJob controller accepts, and prints back in the same syntax.
+type CronSchedule struct {
+	// TimeZonePrefix is the TZ= or CRON_TZ= prefix as written, empty when the schedule has none
+	TimeZonePrefix string
+	// TimeZone is the location named by the prefix
+	TimeZone string
+
+	// Descriptor is the predefined schedule used, such as @hourly, empty for a five field schedule
+	Descriptor string
+	// Every is the interval of an @every schedule
+	Every time.Duration
+
+	// Fields are the five fields of the schedule, or those the descriptor stands for
+	Fields [5]CronField
+}
+
+// CronField is a comma separated list of terms
+type CronField struct {
+	Kind  CronFieldKind
+	Terms []CronTerm
+}
+
+// CronTerm is a single entry of a cron field, such as *, 5, MON-FRI or 0-30/10
+type CronTerm struct {
+	// Star is set for * and ?, Start and End are then the bounds of the field
+	Star  bool
+	Start int
+	End   int
+	// Step is 1 unless the term had a /step
+	Step int
+
+	text string
+}
+
+// ParseCronSchedule parses a cron schedule. The L, W and # extensions some cron implementations have are recognised
+// but rejected, as neither the cron library nor the Kubernetes CronJob controller supports them.
+func ParseCronSchedule(spec string) (*CronSchedule, error) {
+	schedule := &CronSchedule{}
+	spec = strings.TrimSpace(spec)
+
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		i := strings.IndexAny(spec, " \t")
+		if i < 0 {
+			return nil, fmt.Errorf("time zone prefix %q is not followed by a schedule", spec)
+		}
+		schedule.TimeZonePrefix = spec[:i]
+		schedule.TimeZone = spec[strings.Index(spec, "=")+1 : i]
+		if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+			return nil, fmt.Errorf("provided bad location %s: %v", schedule.TimeZone, err)
+		}
+		spec = strings.TrimSpace(spec[i:])
+	}
+
+	if strings.HasPrefix(spec, "@") {
+		return schedule, schedule.parseDescriptor(spec)
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(schedule.Fields) {
+		return nil, fmt.Errorf("expected exactly 5 fields, found %d: %s", len(fields), spec)
+	}
+	for i, text := range fields {
+		field, err := ParseCronField(CronFieldKind(i), text)
+		if err != nil {
+			return nil, err
+		}
+		schedule.Fields[i] = field
+	}
+	return schedule, nil
+}
+
+func (s *CronSchedule) parseDescriptor(spec string) error {
+	if strings.HasPrefix(spec, everyDescriptor) {
+		every, err := time.ParseDuration(strings.TrimSpace(spec[len(everyDescriptor):]))
+		if err != nil {
+			return fmt.Errorf("failed to parse duration %s: %v", spec, err)
+		}
+		if every <= 0 {
+			return fmt.Errorf("@every needs a positive duration: %s", spec)
+		}
+		s.Descriptor = strings.TrimSpace(everyDescriptor)
+		s.Every = every
+		return nil
+	}
+
+	fields, exists := cronDescriptors[spec]
+	if !exists {
+		return fmt.Errorf("unrecognized descriptor: %s", spec)
+	}
+	s.Descriptor = spec
+	expanded, err := ParseCronSchedule(fields)
+	if err != nil {
+		return err
+	}
+	s.Fields = expanded.Fields
+	return nil
+}
+
+// String prints the schedule in the syntax it was parsed from, with whitespace normalised
+func (s *CronSchedule) String() string {
+	var body string
+	switch {
+	case s.Every != 0:
+		body = everyDescriptor + s.Every.String()
+	case s.Descriptor != "":
+		body = s.Descriptor
+	default:
+		fields := make([]string, len(s.Fields))
+		for i, field := range s.Fields {
+			fields[i] = field.String()
+		}
+		body = strings.Join(fields, " ")
+	}
+
+	if s.TimeZonePrefix != "" {
+		return s.TimeZonePrefix + " " + body
+	}
+	return body
+}
+
+// ParseCronField parses a single field of a cron schedule. Empty terms are dropped like the cron library does.
+func ParseCronField(kind CronFieldKind, text string) (CronField, error) {
+	field := CronField{Kind: kind}
+	terms := strings.FieldsFunc(text, func(r rune) bool { return r == ',' })
+	if len(terms) == 0 {
+		return CronField{}, fmt.Errorf("invalid %s field %q: no values", kind, text)
+	}
+	for _, termText := range terms {
+		term, err := parseCronTerm(kind, termText)
+		if err != nil {
+			return CronField{}, err
+		}
+		field.Terms = append(field.Terms, term)
+	}
+	return field, nil
+}
+
+// String prints the field as it was written
+func (f CronField) String() string {
+	terms := make([]string, len(f.Terms))
+	for i, term := range f.Terms {
+		terms[i] = term.String()
+	}
+	return strings.Join(terms, ",")
+}
+
+// IsStar matches the cron library: a field is only a star when written as * or ? without a step, which matters
+// for the day fields where two restricted fields match either day rather than both
+func (f CronField) IsStar() bool {
+	for _, term := range f.Terms {
+		if !term.Star || term.Step > 1 {
+			return false
+		}
+	}
+	return len(f.Terms) > 0
+}
+
+// Values lists the values the field matches in the order they are written, without duplicates
+func (f CronField) Values() []int {
+	seen := map[int]bool{}
+	values := []int{}
+	for _, term := range f.Terms {
+		for value := term.Start; value <= term.End; value += term.Step {
+			if !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// covers reports whether the field matches every value it can hold
+func (f CronField) covers() bool {
+	return len(f.Values()) == cronFields[f.Kind].max-cronFields[f.Kind].min+1
+}
+
+// cronFieldOf builds a field matching the given values, written as a comma separated list in the given order
+func cronFieldOf(kind CronFieldKind, values []int) CronField {
+	field := CronField{Kind: kind}
+	for _, value := range values {
+		field.Terms = append(field.Terms, CronTerm{Start: value, End: value, Step: 1, text: strconv.Itoa(value)})
+	}
+	return field
+}
+
+// String prints the term as it was written
+func (t CronTerm) String() string {
+	return t.text
+}
+
+// cronToken is a lexical token of a cron term
+type cronToken struct {
+	// kind is one of number, word or the symbol itself
+	kind  string
+	text  string
+	value int
+}
+
+func lexCronTerm(text string) ([]cronToken, error) {
+	tokens := []cronToken{}
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(text) && text[j] >= '0' && text[j] <= '9' {
+				j++
+			}
+			value, err := strconv.Atoi(text[i:j])
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse int from %s: %v", text[i:j], err)
+			}
+			tokens = append(tokens, cronToken{kind: "number", text: text[i:j], value: value})
+			i = j
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			j := i
+			for j < len(text) && ((text[j] >= 'a' && text[j] <= 'z') || (text[j] >= 'A' && text[j] <= 'Z')) {
+				j++
+			}
+			tokens = append(tokens, cronToken{kind: "word", text: text[i:j]})
+			i = j
+		case strings.IndexByte("*?-/#", c) >= 0:
+			tokens = append(tokens, cronToken{kind: string(c), text: string(c)})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q in %s", c, text)
+		}
+	}
+	return tokens, nil
+}
+
+// cronTermParser is a recursive descent parser over the tokens of a single term
+type cronTermParser struct {
+	kind   CronFieldKind
+	text   string
+	tokens []cronToken
+	pos    int
+}
+
+func (p *cronTermParser) peek() *cronToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *cronTermParser) accept(kind string) *cronToken {
+	if token := p.peek(); token != nil && token.kind == kind {
+		p.pos++
+		return token
+	}
+	return nil
+}
+
+func (p *cronTermParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid %s field %q: %s", p.kind, p.text, fmt.Sprintf(format, args...))
+}
+
+func (p *cronTermParser) unsupported(extension string) error {
+	return p.errorf("%s is not supported by the Kubernetes CronJob controller", extension)
+}
+
+// value parses a number or a month or weekday name
+func (p *cronTermParser) value() (int, bool, error) {
+	bounds := cronFields[p.kind]
+	if token := p.accept("number"); token != nil {
+		if token.value < bounds.min || token.value > bounds.max {
+			return 0, false, p.errorf("%d is outside %d-%d", token.value, bounds.min, bounds.max)
+		}
+		return token.value, true, nil
+	}
+	if token := p.peek(); token != nil && token.kind == "word" {
+		if value, exists := bounds.names[strings.ToLower(token.text)]; exists {
+			p.pos++
+			return value, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func (p *cronTermParser) word(text string) bool {
+	if token := p.peek(); token != nil && token.kind == "word" && strings.EqualFold(token.text, text) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// term := ( "*" | "?" | value [ "-" value ] ) [ "/" number ]
+//
+// The L, LW, nL, nW and n#k extensions are recognised so they can be reported as unsupported.
+func (p *cronTermParser) term() (CronTerm, error) {
+	bounds := cronFields[p.kind]
+	term := CronTerm{Step: 1, text: p.text}
+
+	switch {
+	case p.accept("*") != nil || p.accept("?") != nil:
+		term.Star = true
+		term.Start, term.End = bounds.min, bounds.max
+	case p.kind == DayOfMonthField && p.word("LW"):
+		return term, p.unsupported("the last weekday of the month (LW)")
+	case (p.kind == DayOfMonthField || p.kind == DayOfWeekField) && p.word("L"):
+		return term, p.unsupported("the last day (L)")
+	default:
+		start, ok, err := p.value()
+		if err != nil {
+			return term, err
+		}
+		if !ok {
+			return term, p.errorf("expected a number or name")
+		}
+		term.Start, term.End = start, start
+
+		switch {
+		case p.accept("-") != nil:
+			end, ok, err := p.value()
+			if err != nil {
+				return term, err
+			}
+			if !ok {
+				return term, p.errorf("expected the end of the range")
+			}
+			if end < start {
+				return term, p.errorf("beginning of range (%d) beyond end of range (%d)", start, end)
+			}
+			term.End = end
+		case p.kind == DayOfWeekField && p.word("L"):
+			return term, p.unsupported("the last weekday of its kind in the month (L)")
+		case p.kind == DayOfMonthField && p.word("W"):
+			return term, p.unsupported("the nearest weekday (W)")
+		case p.kind == DayOfWeekField && p.accept("#") != nil:
+			return term, p.unsupported("the nth weekday of the month (#)")
+		}
+	}
+
+	if p.accept("/") != nil {
+		step := p.accept("number")
+		if step == nil || step.value < 1 {
+			return term, p.errorf("step should be a positive number")
+		}
+		term.Step = step.value
+		// a single value with a step, such as 5/15, runs from the value to the end of the field
+		if !term.Star && term.Start == term.End {
+			term.End = bounds.max
+		}
+	}
+
+	if token := p.peek(); token != nil {
+		return term, p.errorf("unexpected %q", token.text)
+	}
+	return term, nil
+}
+
+func parseCronTerm(kind CronFieldKind, text string) (CronTerm, error) {
+	tokens, err := lexCronTerm(text)
+	if err != nil {
+		return CronTerm{}, fmt.Errorf("invalid %s field: %v", kind, err)
+	}
+	if len(tokens) == 0 {
+		return CronTerm{}, fmt.Errorf("invalid %s field: empty term", kind)
+	}
+
+	parser := &cronTermParser{kind: kind, text: text, tokens: tokens}
+	return parser.term()
+}
+
+// sortedCopy returns the values in ascending order, leaving the original order alone
+func sortedCopy(values []int) []int {
+	sorted := append([]int{}, values...)
+	sort.Ints(sorted)
+	return sorted
+}
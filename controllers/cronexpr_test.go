@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCronSchedule_RoundTripsWhatTheCronLibraryAccepts(t *testing.T) {
+	specs := map[string]string{
+		"30 * * 10 *":                      "30 * * 10 *",
+		"5-50/5 */2 1,15 * ?":              "5-50/5 */2 1,15 * ?",
+		"15/30  5 * * *":                   "15/30 5 * * *",
+		"0 9 * JAN-mar mon-FRI":            "0 9 * JAN-mar mon-FRI",
+		"0 0 1-31/10 feb,Aug sun":          "0 0 1-31/10 feb,Aug sun",
+		"TZ=UTC 0 12 * * *":                "TZ=UTC 0 12 * * *",
+		"CRON_TZ=Europe/Amsterdam @hourly": "CRON_TZ=Europe/Amsterdam @hourly",
+		"0 0 * JAN *,":                     "0 0 * JAN *",
+		"@daily":                           "@daily",
+		"@every 1h30m":                     "@every 1h30m0s",
+	}
+
+	from := time.Date(2020, 1, 29, 12, 7, 0, 0, time.UTC)
+	for spec, printed := range specs {
+		schedule, err := ParseCronSchedule(spec)
+		require.NoError(t, err, spec)
+		require.Equal(t, printed, schedule.String())
+
+		// the printed schedule fires at the same times as the original
+		original, err := cron.ParseStandard(spec)
+		require.NoError(t, err, spec)
+		reprinted, err := cron.ParseStandard(schedule.String())
+		require.NoError(t, err, spec)
+		next := from
+		for i := 0; i < 20; i++ {
+			expected := original.Next(next)
+			require.Equal(t, expected, reprinted.Next(next), spec)
+			next = expected
+		}
+	}
+}
+
+func TestParseCronSchedule_ExpandsFields(t *testing.T) {
+	schedule, err := ParseCronSchedule("15-17,0/30 * 5-20/5 * sat,SUN")
+	require.NoError(t, err)
+
+	require.Equal(t, []int{15, 16, 17, 0, 30}, schedule.Fields[MinuteField].Values())
+	require.True(t, schedule.Fields[HourField].IsStar())
+	require.Equal(t, []int{5, 10, 15, 20}, schedule.Fields[DayOfMonthField].Values())
+	require.Equal(t, []int{6, 0}, schedule.Fields[DayOfWeekField].Values())
+
+	// a stepped star is restricted as far as the day matching is concerned
+	stepped, err := ParseCronField(DayOfMonthField, "*/2")
+	require.NoError(t, err)
+	require.False(t, stepped.IsStar())
+
+	weekly, err := ParseCronSchedule("@weekly")
+	require.NoError(t, err)
+	require.Equal(t, "0 0 * * 0", (&CronSchedule{Fields: weekly.Fields}).String())
+}
+
+func TestParseCronSchedule_RejectsWhatTheCronLibraryRejects(t *testing.T) {
+	specs := []string{
+		"",
+		"* 0 * * * *",
+		"60 * * * *",
+		"* * 0 * *",
+		"* * * * 7",
+		"20-10 * * * *",
+		"*/0 * * * *",
+		"0 0 * foo *",
+		"0 0 * * mon-",
+		"0 0 * JAN- *",
+		"@fortnightly",
+		"@every soon",
+		"TZ=Nowhere/Special 0 0 * * *",
+	}
+
+	for _, spec := range specs {
+		_, err := ParseCronSchedule(spec)
+		require.Error(t, err, spec)
+		_, err = cron.ParseStandard(spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestParseCronSchedule_RejectsUnsupportedExtensions(t *testing.T) {
+	for _, spec := range []string{"0 0 L * *", "0 0 LW * *", "0 0 15W * *", "0 0 * * 5L", "0 0 * * FRI#2"} {
+		_, err := ParseCronSchedule(spec)
+		require.Error(t, err, spec)
+		require.Contains(t, err.Error(), "not supported by the Kubernetes CronJob controller", spec)
+	}
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/go-logr/logr"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// dryRunAction decides what would be done to an autogenerated cronjob, existingCron is nil when it doesn't exist
+func dryRunAction(existingCron *batchv1beta1.CronJob, objectHash string, instance *pscv1alpha1.PreScaledCronJob) pscv1alpha1.DryRunAction {
+	if existingCron == nil {
+		return pscv1alpha1.DryRunCreate
+	}
+
+	owned := false
+	for _, ref := range existingCron.ObjectMeta.OwnerReferences {
+		if ref.UID == instance.UID {
+			owned = true
+			break
+		}
+	}
+	if !owned {
+		return pscv1alpha1.DryRunConflict
+	}
+
+	if existingCron.ObjectMeta.Annotations[objectHashField] == objectHash {
+		return pscv1alpha1.DryRunNoOp
+	}
+	return pscv1alpha1.DryRunUpdate
+}
+
+// dryRun works out what would be done to the cronjob of each schedule, and to the cronjobs of schedules which
+// have been removed, and reports every decision
+func (r *PreScaledCronJobReconciler) dryRun(ctx context.Context, instance *pscv1alpha1.PreScaledCronJob, crons []primerCronJob, logger logr.Logger) (ctrl.Result, error) {
+	decisions := []pscv1alpha1.DryRunCronJob{}
+	for i := range crons {
+		existingCron, err := r.getCronJob(ctx, crons[i].generated.Name, crons[i].generated.Namespace)
+		if err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "Failed to get associated cronjob")
+			return ctrl.Result{}, err
+		}
+		if errors.IsNotFound(err) {
+			existingCron = nil
+		}
+
+		decisions = append(decisions, pscv1alpha1.DryRunCronJob{
+			Action:      dryRunAction(existingCron, crons[i].objectHash, instance),
+			CronJobName: crons[i].generated.Name,
+			ObjectHash:  crons[i].objectHash,
+		})
+	}
+
+	owned, err := r.ownedCronJobs(ctx, instance)
+	if err != nil {
+		logger.Error(err, "Failed to list owned cronjobs")
+		return ctrl.Result{}, err
+	}
+	for _, cron := range staleCronJobs(owned, crons) {
+		decisions = append(decisions, pscv1alpha1.DryRunCronJob{Action: pscv1alpha1.DryRunDelete, CronJobName: cron.Name})
+	}
+
+	return r.reportDryRun(ctx, instance, decisions)
+}
+
+// dryRunDelete reports that the cronjobs of a prescaledcronjob being deleted would be removed
+func (r *PreScaledCronJobReconciler) dryRunDelete(ctx context.Context, instance *pscv1alpha1.PreScaledCronJob, logger logr.Logger) (ctrl.Result, error) {
+	owned, err := r.ownedCronJobs(ctx, instance)
+	if err != nil {
+		logger.Error(err, "Failed to list owned cronjobs")
+		return ctrl.Result{}, err
+	}
+
+	decisions := []pscv1alpha1.DryRunCronJob{}
+	for _, cron := range owned {
+		decisions = append(decisions, pscv1alpha1.DryRunCronJob{Action: pscv1alpha1.DryRunDelete, CronJobName: cron.Name})
+	}
+	// the finalizer would still be released when the cronjobs have already gone
+	if len(decisions) == 0 {
+		decisions = append(decisions, pscv1alpha1.DryRunCronJob{Action: pscv1alpha1.DryRunDelete, CronJobName: autogenName(instance)})
+	}
+	return r.reportDryRun(ctx, instance, decisions)
+}
+
+// reportDryRun records what would be done to the autogenerated cronjobs in status, with an event and metric for
+// each cronjob whose decision changed
+func (r *PreScaledCronJobReconciler) reportDryRun(ctx context.Context, instance *pscv1alpha1.PreScaledCronJob, decisions []pscv1alpha1.DryRunCronJob) (ctrl.Result, error) {
+	previous := map[string]pscv1alpha1.DryRunCronJob{}
+	if instance.Status.DryRun != nil {
+		for _, decision := range instance.Status.DryRun.CronJobs {
+			previous[decision.CronJobName] = decision
+		}
+	}
+
+	changed := []pscv1alpha1.DryRunCronJob{}
+	for _, decision := range decisions {
+		if last, exists := previous[decision.CronJobName]; !exists || last != decision {
+			changed = append(changed, decision)
+		}
+	}
+	if len(changed) == 0 && len(previous) == len(decisions) {
+		return ctrl.Result{}, nil
+	}
+
+	instance.Status.DryRun = &pscv1alpha1.DryRunStatus{
+		CronJobs:          decisions,
+		LastEvaluatedTime: metav1.Now(),
+	}
+	if err := r.Status().Update(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	for _, decision := range changed {
+		TrackDryRunDecision(instance.Name, string(decision.Action))
+		recordEvent(ctx, r.Recorder, instance, corev1.EventTypeNormal, "DryRun", dryRunMessage(decision.Action, decision.CronJobName, decision.ObjectHash))
+	}
+	return ctrl.Result{}, nil
+}
+
+func dryRunMessage(action pscv1alpha1.DryRunAction, cronName string, objectHash string) string {
+	switch action {
+	case pscv1alpha1.DryRunCreate:
+		return fmt.Sprintf("Dry run: would create cronjob %s with hash %s", cronName, objectHash)
+	case pscv1alpha1.DryRunUpdate:
+		return fmt.Sprintf("Dry run: would update cronjob %s to hash %s", cronName, objectHash)
+	case pscv1alpha1.DryRunConflict:
+		return fmt.Sprintf("Dry run: cronjob %s exists and was not created by this operator, it would be left alone", cronName)
+	case pscv1alpha1.DryRunDelete:
+		return fmt.Sprintf("Dry run: would delete cronjob %s with its jobs", cronName)
+	default:
+		return fmt.Sprintf("Dry run: cronjob %s is up to date", cronName)
+	}
+}
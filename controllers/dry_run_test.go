@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestDryRunAction(t *testing.T) {
+	instance := generatePSCSpec()
+	instance.UID = types.UID("psc-uid")
+	r := newTestReconciler(t)
+	cron, err := r.generateCronJob(&instance)
+	require.NoError(t, err)
+	cron.Annotations = map[string]string{objectHashField: "current"}
+
+	notOwned := cron.DeepCopy()
+	notOwned.OwnerReferences = nil
+
+	require.Equal(t, pscv1alpha1.DryRunCreate, dryRunAction(nil, "current", &instance))
+	require.Equal(t, pscv1alpha1.DryRunNoOp, dryRunAction(cron, "current", &instance))
+	require.Equal(t, pscv1alpha1.DryRunUpdate, dryRunAction(cron, "changed", &instance))
+	require.Equal(t, pscv1alpha1.DryRunConflict, dryRunAction(notOwned, "current", &instance))
+}
+
+func TestReconcile_DryRunReportsWithoutWriting(t *testing.T) {
+	instance := generatePSCSpec()
+	r := newTestReconciler(t, &instance)
+	r.DryRun = true
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	ctx := context.Background()
+
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	// no finalizer and no cronjob, only the decision in status
+	fetched := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(ctx, key, fetched))
+	require.Empty(t, fetched.Finalizers)
+	require.Len(t, fetched.Status.DryRun.CronJobs, 1)
+	decision := fetched.Status.DryRun.CronJobs[0]
+	require.Equal(t, pscv1alpha1.DryRunCreate, decision.Action)
+	require.Equal(t, autogenName(&instance), decision.CronJobName)
+	require.NotEmpty(t, decision.ObjectHash)
+
+	cron := &batchv1beta1.CronJob{}
+	err = r.Get(ctx, types.NamespacedName{Name: autogenName(&instance), Namespace: instance.Namespace}, cron)
+	require.True(t, errors.IsNotFound(err))
+}
+
+func TestReconcile_DryRunLeavesDeletedObjectsAlone(t *testing.T) {
+	now := metav1.Now()
+	instance := generatePSCSpec()
+	instance.UID = types.UID("psc-uid")
+	instance.Finalizers = []string{finalizerName}
+	instance.DeletionTimestamp = &now
+
+	r := newTestReconciler(t)
+	cron, err := r.generateCronJob(&instance)
+	require.NoError(t, err)
+	r = newTestReconciler(t, &instance, cron)
+	r.DryRun = true
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	ctx := context.Background()
+
+	_, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	fetched := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(ctx, key, fetched))
+	require.Contains(t, fetched.Finalizers, finalizerName)
+	require.Equal(t, []pscv1alpha1.DryRunCronJob{{Action: pscv1alpha1.DryRunDelete, CronJobName: cron.Name}}, fetched.Status.DryRun.CronJobs)
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: cron.Name, Namespace: cron.Namespace}, &batchv1beta1.CronJob{}))
+}
+
+// updateCounter counts the updates made to objects, leaving out status updates
+type updateCounter struct {
+	client.Client
+	updates int
+}
+
+func (c *updateCounter) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	c.updates++
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestReconcile_DryRunKeepsTheLegacyFinalizer(t *testing.T) {
+	instance := generatePSCSpec()
+	instance.Finalizers = []string{legacyFinalizerName}
+	r := newTestReconciler(t, &instance)
+	counter := &updateCounter{Client: r.Client}
+	r.Client = counter
+	r.DryRun = true
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	require.Zero(t, counter.updates)
+	fetched := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(context.Background(), key, fetched))
+	require.Equal(t, []string{legacyFinalizerName}, fetched.Finalizers)
+	require.NotNil(t, fetched.Status.DryRun)
+}
+
+func TestReconcile_DryRunReportsEveryCronJob(t *testing.T) {
+	instance := newMultiSchedulePSC("daily")
+	instance.UID = types.UID("psc-uid")
+
+	// the weekday cronjob is up to date and a holiday schedule has since been removed
+	r := newTestReconciler(t)
+	weekday := ScheduleInstances(&instance)[0]
+	upToDate, err := r.generateCronJob(weekday)
+	require.NoError(t, err)
+	objectHash, err := Hash(upToDate, 1)
+	require.NoError(t, err)
+	upToDate.Annotations = map[string]string{objectHashField: objectHash}
+
+	holiday := instance.DeepCopy()
+	holiday.Spec.Schedules = []pscv1alpha1.ScheduleEntry{{Name: "holiday", Schedule: "0 9 25 12 *"}}
+	stale, err := r.generateCronJob(ScheduleInstances(holiday)[0])
+	require.NoError(t, err)
+
+	r = newTestReconciler(t, &instance, upToDate, stale)
+	r.DryRun = true
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	ctx := context.Background()
+
+	_, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	fetched := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(ctx, key, fetched))
+	actions := map[string]pscv1alpha1.DryRunAction{}
+	for _, decision := range fetched.Status.DryRun.CronJobs {
+		actions[decision.CronJobName] = decision.Action
+	}
+	require.Equal(t, map[string]pscv1alpha1.DryRunAction{
+		"autogen-daily-weekday": pscv1alpha1.DryRunNoOp,
+		"autogen-daily-weekend": pscv1alpha1.DryRunCreate,
+		"autogen-daily-holiday": pscv1alpha1.DryRunDelete,
+	}, actions)
+
+	// nothing was written to the cronjobs
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: stale.Name, Namespace: stale.Namespace}, &batchv1beta1.CronJob{}))
+	err = r.Get(ctx, types.NamespacedName{Name: "autogen-daily-weekend", Namespace: instance.Namespace}, &batchv1beta1.CronJob{})
+	require.True(t, errors.IsNotFound(err))
+}
@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+const (
+	// exclusionHorizon is how far ahead runs are checked against the exclusion calendars
+	exclusionHorizon = time.Hour * 24 * 31
+	// maxUpcomingSkips bounds how many skipped runs are listed in status
+	maxUpcomingSkips = 10
+
+	exclusionDateFormat = "2006-01-02"
+)
+
+// exclusionWindow is a span of excluded time, from the start of its first day to the end of its last
+type exclusionWindow struct {
+	calendar string
+	reason   string
+	from     time.Time
+	until    time.Time
+}
+
+// exclusionWindowsFor turns the days of the calendars a prescaledcronjob references into spans of time
+func exclusionWindowsFor(ctx context.Context, c client.Reader, instance *pscv1alpha1.PreScaledCronJob) ([]exclusionWindow, error) {
+	windows := []exclusionWindow{}
+	for _, name := range instance.Spec.ExclusionCalendars {
+		calendar := &pscv1alpha1.ExclusionCalendar{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name}, calendar); err != nil {
+			return nil, fmt.Errorf("failed to get exclusion calendar %s: %s", name, err)
+		}
+
+		calendarWindows, err := calendarWindows(calendar)
+		if err != nil {
+			return nil, permanentError(err)
+		}
+		windows = append(windows, calendarWindows...)
+	}
+	return windows, nil
+}
+
+func calendarWindows(calendar *pscv1alpha1.ExclusionCalendar) ([]exclusionWindow, error) {
+	location := time.UTC
+	if calendar.Spec.TimeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(calendar.Spec.TimeZone); err != nil {
+			return nil, fmt.Errorf("exclusion calendar %s has an invalid time zone: %s", calendar.Name, err)
+		}
+	}
+
+	windows := make([]exclusionWindow, 0, len(calendar.Spec.Exclusions))
+	for _, exclusion := range calendar.Spec.Exclusions {
+		start, err := time.ParseInLocation(exclusionDateFormat, exclusion.Start, location)
+		if err != nil {
+			return nil, fmt.Errorf("exclusion calendar %s has an invalid start: %s", calendar.Name, err)
+		}
+		end := start
+		if exclusion.End != "" {
+			if end, err = time.ParseInLocation(exclusionDateFormat, exclusion.End, location); err != nil {
+				return nil, fmt.Errorf("exclusion calendar %s has an invalid end: %s", calendar.Name, err)
+			}
+			if end.Before(start) {
+				return nil, fmt.Errorf("exclusion calendar %s ends %s before it starts %s", calendar.Name, exclusion.End, exclusion.Start)
+			}
+		}
+
+		windows = append(windows, exclusionWindow{
+			calendar: calendar.Name,
+			reason:   exclusion.Reason,
+			from:     start,
+			// AddDate keeps to midnight across daylight saving changes
+			until: end.AddDate(0, 0, 1),
+		})
+	}
+	return windows, nil
+}
+
+// excludedBy returns the window a time falls in, if any
+func excludedBy(windows []exclusionWindow, at time.Time) *exclusionWindow {
+	for i := range windows {
+		if !at.Before(windows[i].from) && at.Before(windows[i].until) {
+			return &windows[i]
+		}
+	}
+	return nil
+}
+
+// upcomingSkips lists the runs of a single schedule whose workload hasn't started yet and falls in an excluded
+// window, and whether the next primer to fire is for one of them
+func upcomingSkips(instance *pscv1alpha1.PreScaledCronJob, windows []exclusionWindow, now time.Time) ([]pscv1alpha1.SkippedRun, bool) {
+	if len(windows) == 0 {
+		return nil, false
+	}
+
+	// primers which have fired already may still be warming up for a run that hasn't started
+	runs, err := scheduleRuns(instance, now.Add(-time.Hour*24), now.Add(exclusionHorizon))
+	if err != nil {
+		// invalid schedules are reported when the cronjob is generated
+		return nil, false
+	}
+
+	skips := []pscv1alpha1.SkippedRun{}
+	nextExcluded, nextSeen := false, false
+	for _, run := range runs {
+		if !run.WorkloadAt.After(now) {
+			continue
+		}
+		window := excludedBy(windows, run.WorkloadAt)
+		if !nextSeen && run.PrimerAt.After(now) {
+			nextSeen = true
+			nextExcluded = window != nil
+		}
+		if window != nil && len(skips) < maxUpcomingSkips {
+			skips = append(skips, pscv1alpha1.SkippedRun{
+				WorkloadTime: metav1.NewTime(run.WorkloadAt),
+				Calendar:     window.calendar,
+				Reason:       window.reason,
+				Schedule:     run.Schedule,
+			})
+		}
+	}
+
+	if len(skips) == 0 {
+		return nil, nextExcluded
+	}
+	return skips, nextExcluded
+}
+
+// reconcileExclusions records the upcoming skipped runs in status and reports, by schedule name, which cronjobs
+// should be suspended because their next primer warms up for an excluded day. The cronjobs are checked again
+// shortly before each primer fires, which lifts the suspension once the excluded days have passed.
+func (r *PreScaledCronJobReconciler) reconcileExclusions(ctx context.Context, instance *pscv1alpha1.PreScaledCronJob) (map[string]bool, error) {
+	windows, err := exclusionWindowsFor(ctx, r.policyReader(), instance)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var skips []pscv1alpha1.SkippedRun
+	excluded := map[string]bool{}
+	for _, view := range ScheduleInstances(instance) {
+		scheduleSkips, nextExcluded := upcomingSkips(view, windows, now)
+		skips = append(skips, scheduleSkips...)
+		excluded[ScheduleName(view)] = nextExcluded
+	}
+
+	sort.SliceStable(skips, func(i, j int) bool {
+		return skips[i].WorkloadTime.Before(&skips[j].WorkloadTime)
+	})
+	if len(skips) > maxUpcomingSkips {
+		skips = skips[:maxUpcomingSkips]
+	}
+
+	if equality.Semantic.DeepEqual(skips, instance.Status.UpcomingSkips) {
+		return excluded, nil
+	}
+
+	instance.Status.UpcomingSkips = skips
+	return excluded, r.Status().Update(ctx, instance)
+}
+
+// isSkippedRun returns the skipped run with the given workload time, nil when the run isn't skipped. The
+// prescaledcronjob is the one returned by ScheduleInstances for the schedule of the run.
+func isSkippedRun(instance *pscv1alpha1.PreScaledCronJob, workloadAt time.Time) *pscv1alpha1.SkippedRun {
+	for i := range instance.Status.UpcomingSkips {
+		skip := &instance.Status.UpcomingSkips[i]
+		if skip.WorkloadTime.Time.Equal(workloadAt) && skip.Schedule == ScheduleName(instance) {
+			return skip
+		}
+	}
+	return nil
+}
+
+// reconcileExclusion deletes the job of a pod warming up for an excluded run and reports whether it did. Runs are
+// normally stopped by suspending the cronjob, this catches a primer which fired anyway, for example a missed run
+// started when the suspension was lifted.
+func (r *PodReconciler) reconcileExclusion(ctx context.Context, pod *corev1.Pod, instance *pscv1alpha1.PreScaledCronJob, workloadAt time.Time) (bool, error) {
+	skip := isSkippedRun(instance, workloadAt)
+	if skip == nil {
+		return false, nil
+	}
+
+	job := &batchv1.Job{}
+	if err := r.Get(ctx, types.NamespacedName{Name: jobNameFor(pod), Namespace: pod.Namespace}, job); err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	if job.DeletionTimestamp != nil {
+		return true, nil
+	}
+
+	if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+
+	message := fmt.Sprintf("Job %s skipped, its workload time %s is excluded by calendar %s", job.Name, workloadAt.Format(time.RFC3339), skip.Calendar)
+	if skip.Reason != "" {
+		message += ": " + skip.Reason
+	}
+	recordEvent(ctx, r.Recorder, instance, corev1.EventTypeNormal, "ExcludedRun", message)
+	return true, nil
+}
+
+// requestsForCalendarUsers maps a change to an ExclusionCalendar onto the prescaledcronjobs referencing it
+func (r *PreScaledCronJobReconciler) requestsForCalendarUsers(obj handler.MapObject) []ctrl.Request {
+	list := &pscv1alpha1.PreScaledCronJobList{}
+	if err := r.List(context.Background(), list); err != nil {
+		r.Log.Error(err, "Failed to list prescaledcronjobs for exclusion calendar change")
+		return nil
+	}
+
+	requests := []ctrl.Request{}
+	for _, item := range list.Items {
+		if containsString(item.Spec.ExclusionCalendars, obj.Meta.GetName()) {
+			requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Name: item.Name, Namespace: item.Namespace}})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func newCalendar(name string, timeZone string, exclusions ...pscv1alpha1.Exclusion) *pscv1alpha1.ExclusionCalendar {
+	return &pscv1alpha1.ExclusionCalendar{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       pscv1alpha1.ExclusionCalendarSpec{TimeZone: timeZone, Exclusions: exclusions},
+	}
+}
+
+func TestCalendarWindows(t *testing.T) {
+	calendar := newCalendar("holidays", "Europe/London",
+		pscv1alpha1.Exclusion{Start: "2020-12-25", Reason: "Christmas Day"},
+		pscv1alpha1.Exclusion{Start: "2020-03-28", End: "2020-03-29", Reason: "Clocks change"},
+	)
+	windows, err := calendarWindows(calendar)
+	require.NoError(t, err)
+
+	require.Nil(t, excludedBy(windows, time.Date(2020, 12, 24, 23, 59, 0, 0, time.UTC)))
+	require.Equal(t, "Christmas Day", excludedBy(windows, time.Date(2020, 12, 25, 0, 0, 0, 0, time.UTC)).reason)
+	require.Nil(t, excludedBy(windows, time.Date(2020, 12, 26, 0, 0, 0, 0, time.UTC)))
+
+	// the range ends at midnight London time, an hour earlier in UTC once the clocks have gone forward
+	require.NotNil(t, excludedBy(windows, time.Date(2020, 3, 29, 22, 59, 0, 0, time.UTC)))
+	require.Nil(t, excludedBy(windows, time.Date(2020, 3, 29, 23, 0, 0, 0, time.UTC)))
+
+	_, err = calendarWindows(newCalendar("bad", "", pscv1alpha1.Exclusion{Start: "2020-12-25", End: "2020-12-24"}))
+	require.Error(t, err)
+	_, err = calendarWindows(newCalendar("bad", "Nowhere/Special", pscv1alpha1.Exclusion{Start: "2020-12-25"}))
+	require.Error(t, err)
+}
+
+func TestUpcomingSkips(t *testing.T) {
+	instance := newPreviewPSC("nightly", "0 0 * * *", 10, "")
+	windows, err := calendarWindows(newCalendar("holidays", "",
+		pscv1alpha1.Exclusion{Start: "2020-12-25", End: "2020-12-26", Reason: "Christmas"}))
+	require.NoError(t, err)
+
+	// the primer for the 25th fires at 23:50 on the 24th
+	skips, nextExcluded := upcomingSkips(&instance, windows, time.Date(2020, 12, 24, 12, 0, 0, 0, time.UTC))
+	require.True(t, nextExcluded)
+	require.Equal(t, []pscv1alpha1.SkippedRun{
+		{WorkloadTime: metav1.NewTime(time.Date(2020, 12, 25, 0, 0, 0, 0, time.UTC)), Calendar: "holidays", Reason: "Christmas"},
+		{WorkloadTime: metav1.NewTime(time.Date(2020, 12, 26, 0, 0, 0, 0, time.UTC)), Calendar: "holidays", Reason: "Christmas"},
+	}, skips)
+
+	// the primer has fired, the run is still listed until its workload time
+	skips, nextExcluded = upcomingSkips(&instance, windows, time.Date(2020, 12, 24, 23, 55, 0, 0, time.UTC))
+	require.True(t, nextExcluded)
+	require.Len(t, skips, 2)
+
+	// the last excluded run has been warmed up for, the next primer can fire
+	skips, nextExcluded = upcomingSkips(&instance, windows, time.Date(2020, 12, 25, 23, 55, 0, 0, time.UTC))
+	require.False(t, nextExcluded)
+	require.Len(t, skips, 1)
+
+	skips, nextExcluded = upcomingSkips(&instance, nil, time.Date(2020, 12, 24, 12, 0, 0, 0, time.UTC))
+	require.False(t, nextExcluded)
+	require.Nil(t, skips)
+}
+
+func TestReconcile_SuspendsTheCronJobAroundExcludedDays(t *testing.T) {
+	today := time.Now().UTC()
+	calendar := newCalendar("freeze", "", pscv1alpha1.Exclusion{
+		Start:  today.Format(exclusionDateFormat),
+		End:    today.AddDate(0, 0, 2).Format(exclusionDateFormat),
+		Reason: "Change freeze",
+	})
+
+	instance := generatePSCSpec()
+	instance.Finalizers = []string{finalizerName}
+	instance.Spec.CronJob.Spec.Schedule = "30 * * * *"
+	instance.Spec.ExclusionCalendars = []string{calendar.Name}
+	r := newTestReconciler(t, &instance, calendar)
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	ctx := context.Background()
+
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	fetched := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(ctx, key, fetched))
+	require.Len(t, fetched.Status.UpcomingSkips, maxUpcomingSkips)
+	require.Equal(t, "Change freeze", fetched.Status.UpcomingSkips[0].Reason)
+	require.Nil(t, findCondition(&fetched.Status, pscv1alpha1.PreflightFailed))
+
+	cron := &batchv1beta1.CronJob{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: autogenName(fetched), Namespace: fetched.Namespace}, cron))
+	require.True(t, *cron.Spec.Suspend)
+
+	// removing the calendar lifts the suspension
+	fetched.Spec.ExclusionCalendars = nil
+	require.NoError(t, r.Update(ctx, fetched))
+	_, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	resumed := &batchv1beta1.CronJob{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: autogenName(fetched), Namespace: fetched.Namespace}, resumed))
+	require.False(t, resumed.Spec.Suspend != nil && *resumed.Spec.Suspend)
+	updated := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(ctx, key, updated))
+	require.Empty(t, updated.Status.UpcomingSkips)
+}
+
+func TestReconcile_MissingExclusionCalendarIsRetried(t *testing.T) {
+	instance := generatePSCSpec()
+	instance.Finalizers = []string{finalizerName}
+	instance.Spec.ExclusionCalendars = []string{"missing"}
+	r := newTestReconciler(t, &instance)
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+
+	result, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+	require.Equal(t, retryBackoff(1), result.RequeueAfter)
+
+	fetched := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(context.Background(), key, fetched))
+	require.Contains(t, fetched.Status.LastError, "exclusion calendar missing")
+}
+
+func TestReconcileExclusion_DeletesTheJobOfAnExcludedRun(t *testing.T) {
+	instance, pod, job := newLateRun("")
+	r := newTestPodReconciler(t, pod, job)
+	ctx := context.Background()
+	workloadAt, err := workloadTimeForPod(pod, instance)
+	require.NoError(t, err)
+
+	skipped, err := r.reconcileExclusion(ctx, pod, instance, workloadAt)
+	require.NoError(t, err)
+	require.False(t, skipped)
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: job.Name, Namespace: namespace}, &batchv1.Job{}))
+
+	instance.Status.UpcomingSkips = []pscv1alpha1.SkippedRun{{WorkloadTime: metav1.NewTime(workloadAt), Calendar: "holidays"}}
+	skipped, err = r.reconcileExclusion(ctx, pod, instance, workloadAt)
+	require.NoError(t, err)
+	require.True(t, skipped)
+
+	err = r.Get(ctx, types.NamespacedName{Name: job.Name, Namespace: namespace}, &batchv1.Job{})
+	require.True(t, errors.IsNotFound(err))
+}
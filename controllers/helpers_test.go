@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"testing"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// defaultNodepoolLabel is the node selector key the tests target nodepools with
+var defaultNodepoolLabel = defaultTunables().NodepoolLabel
+
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	testScheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(testScheme))
+	require.NoError(t, pscv1alpha1.AddToScheme(testScheme))
+	return testScheme
+}
+
+// newTestClient is a fake client holding objs, which the reconcilers below are built on
+func newTestClient(t *testing.T, objs ...runtime.Object) client.Client {
+	return fake.NewFakeClientWithScheme(newTestScheme(t), objs...)
+}
+
+func newTestReconciler(t *testing.T, objs ...runtime.Object) *PreScaledCronJobReconciler {
+	testScheme := newTestScheme(t)
+	return &PreScaledCronJobReconciler{
+		Client:             fake.NewFakeClientWithScheme(testScheme, objs...),
+		Scheme:             testScheme,
+		Log:                ctrl.Log.WithName("test"),
+		Recorder:           record.NewFakeRecorder(100),
+		InitContainerImage: "initcontainer:1",
+	}
+}
+
+func newTestPodReconciler(t *testing.T, objs ...runtime.Object) *PodReconciler {
+	return &PodReconciler{
+		Client:   newTestClient(t, objs...),
+		Log:      ctrl.Log.WithName("test"),
+		Recorder: record.NewFakeRecorder(100),
+	}
+}
+
+func newTestJobReconciler(t *testing.T, objs ...runtime.Object) *JobReconciler {
+	return &JobReconciler{
+		Client:   newTestClient(t, objs...),
+		Log:      ctrl.Log.WithName("test"),
+		Recorder: record.NewFakeRecorder(100),
+	}
+}
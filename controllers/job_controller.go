@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// RunRecordedAnnotation is set on a finished job once its outcome has been recorded against its
+	// prescaledcronjob, so it isn't counted again when the operator restarts
+	RunRecordedAnnotation = "psc.cronprimer.local/run-recorded"
+
+	warmUpPhase   = "warmup"
+	workloadPhase = "workload"
+)
+
+// JobReconciler follows the jobs created by the generated cronjobs through to completion and records how each
+// prescaled run ended
+type JobReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+
+	// Pods reads the pods of a job, falling back to Client when not set
+	Pods client.Reader
+
+	// DryRun leaves jobs untouched, their outcome is still recorded in metrics and status but without the
+	// annotation a restart can count it again
+	DryRun bool
+}
+
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=psc.cronprimer.local,resources=prescaledcronjobs/status,verbs=get;update;patch
+
+// Reconcile records the outcome of a finished job against the prescaledcronjob whose cronjob created it
+func (r *JobReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx, logger := startReconcile(r.Log, "job", req.NamespacedName)
+
+	job := &batchv1.Job{}
+	if err := r.Get(ctx, req.NamespacedName, job); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get job")
+		return ctrl.Result{}, err
+	}
+
+	prescaledName, primed := job.Spec.Template.Labels[PrimedCronLabel]
+	if !primed || job.Annotations[RunRecordedAnnotation] != "" {
+		return ctrl.Result{}, nil
+	}
+	result, finishedAt, finished := jobResult(job)
+	if !finished {
+		return ctrl.Result{}, nil
+	}
+
+	instance := &pscv1alpha1.PreScaledCronJob{}
+	if err := r.Get(ctx, types.NamespacedName{Name: prescaledName, Namespace: job.Namespace}, instance); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get parent prescaledcronjob")
+		return ctrl.Result{}, err
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.podReader().List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{jobNameLabel: job.Name}); err != nil {
+		logger.Error(err, "Failed to list the job's pods")
+		return ctrl.Result{}, err
+	}
+	warmUp, nodeTime := runNodeTime(pods.Items, finishedAt.Time)
+	outcome := runOutcome(job, result, finishedAt, warmUp, nodeTime)
+
+	// a job whose status was written but not its annotation is already counted
+	recorded := instance.Status.LastRun != nil && instance.Status.LastRun.JobName == job.Name
+	if !recorded {
+		recordRunOutcome(&instance.Status, outcome)
+		if err := r.Status().Update(ctx, instance); err != nil {
+			logger.Error(err, "Failed to record the run's outcome")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if !r.DryRun {
+		patch := client.MergeFrom(job.DeepCopy())
+		if job.Annotations == nil {
+			job.Annotations = map[string]string{}
+		}
+		job.Annotations[RunRecordedAnnotation] = string(result)
+		if err := r.Patch(ctx, job, patch); err != nil {
+			logger.Error(err, "Failed to mark the job as recorded")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if recorded {
+		return ctrl.Result{}, nil
+	}
+	logger.Info("Recorded run outcome", "prescaledcronjob", instance.Name, "result", result, "duration", outcome.Duration.Duration.String(), "retries", outcome.Retries)
+
+	TrackRunOutcome(instance.Name, result == pscv1alpha1.RunSucceeded, outcome.Duration.Duration, outcome.Retries)
+	TrackRunNodeTime(instance.Name, nodepoolFor(&job.Spec.Template.Spec), warmUp, nodeTime)
+
+	if result == pscv1alpha1.RunSucceeded {
+		recordEvent(ctx, r.Recorder, instance, corev1.EventTypeNormal, "RunSucceeded", fmt.Sprintf("Job %s completed after %s with %d retries", job.Name, outcome.Duration.Duration, outcome.Retries))
+	} else {
+		recordEvent(ctx, r.Recorder, instance, corev1.EventTypeWarning, "RunFailed", fmt.Sprintf("Job %s failed after %s with %d retries", job.Name, outcome.Duration.Duration, outcome.Retries))
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *JobReconciler) podReader() client.Reader {
+	if r.Pods != nil {
+		return r.Pods
+	}
+	return r.Client
+}
+
+// jobResult reports whether a job has completed or failed, and when
+func jobResult(job *batchv1.Job) (pscv1alpha1.RunResult, metav1.Time, bool) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return pscv1alpha1.RunSucceeded, condition.LastTransitionTime, true
+		case batchv1.JobFailed:
+			return pscv1alpha1.RunFailed, condition.LastTransitionTime, true
+		}
+	}
+	return "", metav1.Time{}, false
+}
+
+// runOutcome describes how a finished job went. Its duration is measured from the workload time it was stamped
+// with, so the warm-up isn't counted, falling back to when the job started for jobs which were never stamped.
+func runOutcome(job *batchv1.Job, result pscv1alpha1.RunResult, finishedAt metav1.Time, warmUp time.Duration, nodeTime time.Duration) pscv1alpha1.RunOutcome {
+	outcome := pscv1alpha1.RunOutcome{
+		JobName:    job.Name,
+		Schedule:   job.Spec.Template.Labels[ScheduleLabel],
+		Result:     result,
+		FinishTime: finishedAt,
+		Retries:    job.Status.Failed,
+	}
+
+	var startedAt time.Time
+	if workloadAt, err := time.Parse(time.RFC3339, job.Annotations[WorkloadTimeAnnotation]); err == nil {
+		outcome.WorkloadTime = &metav1.Time{Time: workloadAt}
+		startedAt = workloadAt
+	} else if job.Status.StartTime != nil {
+		startedAt = job.Status.StartTime.Time
+	}
+
+	duration := time.Duration(0)
+	if !startedAt.IsZero() && finishedAt.After(startedAt) {
+		duration = finishedAt.Sub(startedAt)
+	}
+	outcome.Duration = &metav1.Duration{Duration: duration}
+
+	if nodeTime > 0 {
+		percent := int32(warmUp * 100 / nodeTime)
+		outcome.WarmUpPercent = &percent
+	}
+	return outcome
+}
+
+// recordRunOutcome counts a finished run, making it the last run unless a later one has been recorded already
+func recordRunOutcome(status *pscv1alpha1.PreScaledCronJobStatus, outcome pscv1alpha1.RunOutcome) {
+	if outcome.Result == pscv1alpha1.RunSucceeded {
+		status.SucceededRuns++
+	} else {
+		status.FailedRuns++
+	}
+
+	if status.LastRun == nil || !outcome.FinishTime.Before(&status.LastRun.FinishTime) {
+		status.LastRun = &outcome
+	}
+}
+
+// runNodeTime adds up how long the pods of a job held their nodes and how much of that was spent in the warm-up
+// container. Pods still running when the job finished are counted up to then.
+func runNodeTime(pods []corev1.Pod, finishedAt time.Time) (warmUp time.Duration, total time.Duration) {
+	for _, pod := range pods {
+		if pod.Status.StartTime == nil {
+			continue
+		}
+
+		endedAt := time.Time{}
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			for _, state := range []corev1.ContainerState{status.State, status.LastTerminationState} {
+				if state.Terminated == nil {
+					continue
+				}
+				if state.Terminated.FinishedAt.After(endedAt) {
+					endedAt = state.Terminated.FinishedAt.Time
+				}
+				if status.Name == warmupContainerInjectNameUID {
+					warmUp += state.Terminated.FinishedAt.Sub(state.Terminated.StartedAt.Time)
+				}
+			}
+		}
+		if endedAt.IsZero() || pod.Status.Phase == corev1.PodRunning {
+			endedAt = finishedAt
+		}
+
+		if endedAt.After(pod.Status.StartTime.Time) {
+			total += endedAt.Sub(pod.Status.StartTime.Time)
+		}
+	}
+	return warmUp, total
+}
+
+// SetupWithManager sets up defaults
+func (r *JobReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// only the jobs of generated cronjobs are interesting, which carry the label injected into their pod template,
+	// and once a job has gone there's nothing left to record
+	filter := predicate.Funcs{
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isPrimedJob(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isPrimedJob(e.ObjectNew)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return isPrimedJob(e.Object)
+		},
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&batchv1.Job{}).
+		WithEventFilter(filter).
+		Complete(r)
+}
+
+func isPrimedJob(obj runtime.Object) bool {
+	job, ok := obj.(*batchv1.Job)
+	if !ok {
+		return false
+	}
+	_, primed := job.Spec.Template.Labels[PrimedCronLabel]
+	return primed
+}
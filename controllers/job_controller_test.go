@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func at(hour int, minute int, second int) metav1.Time {
+	return metav1.NewTime(time.Date(2020, 1, 29, hour, minute, second, 0, time.UTC))
+}
+
+func newFinishedJob(name string, conditionType batchv1.JobConditionType, finishedAt metav1.Time) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Annotations: map[string]string{WorkloadTimeAnnotation: "2020-01-29T12:30:00Z"},
+		},
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{PrimedCronLabel: "hourly"}},
+			},
+		},
+		Status: batchv1.JobStatus{
+			Failed: 1,
+			Conditions: []batchv1.JobCondition{
+				{Type: conditionType, Status: corev1.ConditionTrue, LastTransitionTime: finishedAt},
+			},
+		},
+	}
+}
+
+func TestJobReconcile_RecordsTheRunOnce(t *testing.T) {
+	instance := newPreviewPSC("hourly", "30 * * * *", 10, "")
+	jobName := fmt.Sprintf("autogen-hourly-%d", time.Date(2020, 1, 29, 12, 20, 0, 0, time.UTC).Unix())
+	job := newFinishedJob(jobName, batchv1.JobComplete, at(12, 45, 0))
+
+	// the pod held its node for 25 minutes of which 9m50s were spent warming up
+	pod := newPrimedPod(jobName, jobName+"-abcde")
+	startedAt := at(12, 20, 0)
+	pod.Status.StartTime = &startedAt
+	pod.Status.InitContainerStatuses = []corev1.ContainerStatus{
+		{Name: warmupContainerInjectNameUID, State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{StartedAt: at(12, 20, 10), FinishedAt: at(12, 30, 0)}}},
+	}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{Name: "workload", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{StartedAt: at(12, 30, 5), FinishedAt: at(12, 45, 0)}}},
+	}
+
+	r := newTestJobReconciler(t, &instance, job, pod)
+	recorder := r.Recorder.(*record.FakeRecorder)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: jobName, Namespace: namespace}}
+
+	_, err := r.Reconcile(req)
+	require.NoError(t, err)
+
+	fetched := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: instance.Name, Namespace: namespace}, fetched))
+	require.Equal(t, int32(1), fetched.Status.SucceededRuns)
+	require.Equal(t, int32(0), fetched.Status.FailedRuns)
+	lastRun := fetched.Status.LastRun
+	require.NotNil(t, lastRun)
+	require.Equal(t, jobName, lastRun.JobName)
+	require.Equal(t, pscv1alpha1.RunSucceeded, lastRun.Result)
+	require.Equal(t, time.Minute*15, lastRun.Duration.Duration)
+	require.Equal(t, int32(1), lastRun.Retries)
+	require.Equal(t, int32(39), *lastRun.WarmUpPercent)
+	require.Len(t, recorder.Events, 1)
+
+	recordedJob := &batchv1.Job{}
+	require.NoError(t, r.Get(ctx, req.NamespacedName, recordedJob))
+	require.Equal(t, string(pscv1alpha1.RunSucceeded), recordedJob.Annotations[RunRecordedAnnotation])
+
+	// seeing the job again doesn't count it twice
+	_, err = r.Reconcile(req)
+	require.NoError(t, err)
+	fetched = &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: instance.Name, Namespace: namespace}, fetched))
+	require.Equal(t, int32(1), fetched.Status.SucceededRuns)
+	require.Len(t, recorder.Events, 1)
+}
+
+func TestRecordRunOutcome_KeepsTheLatestRun(t *testing.T) {
+	status := pscv1alpha1.PreScaledCronJobStatus{}
+	recordRunOutcome(&status, pscv1alpha1.RunOutcome{JobName: "later", Result: pscv1alpha1.RunFailed, FinishTime: at(13, 45, 0)})
+	recordRunOutcome(&status, pscv1alpha1.RunOutcome{JobName: "earlier", Result: pscv1alpha1.RunSucceeded, FinishTime: at(12, 45, 0)})
+
+	require.Equal(t, int32(1), status.SucceededRuns)
+	require.Equal(t, int32(1), status.FailedRuns)
+	require.Equal(t, "later", status.LastRun.JobName)
+}
+
+func TestJobResult_IgnoresRunningJobs(t *testing.T) {
+	job := newFinishedJob("autogen-hourly-1", batchv1.JobFailed, at(12, 45, 0))
+	result, finishedAt, finished := jobResult(job)
+	require.True(t, finished)
+	require.Equal(t, pscv1alpha1.RunFailed, result)
+	require.Equal(t, at(12, 45, 0), finishedAt)
+
+	job.Status.Conditions = nil
+	_, _, finished = jobResult(job)
+	require.False(t, finished)
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// LatenessAnnotation is set on a job the operator failed for starting later than its maxLateness
+	LatenessAnnotation = "psc.cronprimer.local/lateness"
+
+	lateRunSkipped = "skipped"
+	lateRunFailed  = "failed"
+
+	// lateExitCode is what the warm-up container exits with when it finishes later than the max lateness,
+	// which stops the workload starting before the operator has dealt with the job
+	lateExitCode = 3
+)
+
+// latenessPolicy is what happens to a late run of the prescaledcronjob
+func latenessPolicy(instance *pscv1alpha1.PreScaledCronJob) pscv1alpha1.LatenessPolicy {
+	if instance.Spec.LatenessPolicy == "" {
+		return pscv1alpha1.SkipLateRun
+	}
+	return instance.Spec.LatenessPolicy
+}
+
+// warmUpExit reports whether the warm-up container of a pod has released the workload, or gave up because it was late
+func warmUpExit(pod *corev1.Pod) (released bool, late bool) {
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name != warmupContainerInjectNameUID {
+			continue
+		}
+		for _, state := range []corev1.ContainerState{status.State, status.LastTerminationState} {
+			if state.Terminated == nil {
+				continue
+			}
+			switch state.Terminated.ExitCode {
+			case 0:
+				released = true
+			case lateExitCode:
+				late = true
+			}
+		}
+	}
+	return released, late
+}
+
+// lateRunDecision decides whether a pod's run is too late to start, and if not how long until it would be
+func lateRunDecision(pod *corev1.Pod, workloadAt time.Time, maxLateness time.Duration, now time.Time) (late bool, wait time.Duration) {
+	released, exitedLate := warmUpExit(pod)
+	if released {
+		return false, 0
+	}
+	if exitedLate {
+		return true, 0
+	}
+
+	deadline := workloadAt.Add(maxLateness)
+	if !now.Before(deadline) {
+		return true, 0
+	}
+	return false, deadline.Sub(now)
+}
+
+// reconcileLateness skips or fails the job of a pod which hasn't started its workload by the max lateness
+func (r *PodReconciler) reconcileLateness(ctx context.Context, pod *corev1.Pod, instance *pscv1alpha1.PreScaledCronJob, workloadAt time.Time) (ctrl.Result, error) {
+	if instance.Spec.MaxLateness == nil {
+		return ctrl.Result{}, nil
+	}
+	// the barrier holds pods past the workload time on purpose, a held pod comes back here once it is released
+	if heldAtBarrier(pod, instance) {
+		return ctrl.Result{}, nil
+	}
+
+	now := time.Now()
+	late, wait := lateRunDecision(pod, workloadAt, instance.Spec.MaxLateness.Duration, now)
+	if !late {
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	job := &batchv1.Job{}
+	if err := r.Get(ctx, types.NamespacedName{Name: jobNameFor(pod), Namespace: pod.Namespace}, job); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	// every pod of the job is late, only the first one seen deals with it
+	if job.DeletionTimestamp != nil || job.Annotations[LatenessAnnotation] != "" {
+		return ctrl.Result{}, nil
+	}
+
+	outcome := lateRunSkipped
+	if latenessPolicy(instance) == pscv1alpha1.FailLateRun {
+		outcome = lateRunFailed
+		patch := client.MergeFrom(job.DeepCopy())
+		if job.Annotations == nil {
+			job.Annotations = map[string]string{}
+		}
+		job.Annotations[LatenessAnnotation] = lateRunFailed
+		// the job controller fails a job past its active deadline straight away, killing its pods
+		activeDeadline := int64(1)
+		job.Spec.ActiveDeadlineSeconds = &activeDeadline
+		if err := r.Patch(ctx, job, patch); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	TrackLateRun(instance.Name, outcome)
+	recordEvent(ctx, r.Recorder, instance, corev1.EventTypeWarning, "LateRun",
+		fmt.Sprintf("Job %s %s, it was %s past its workload time which is more than the max lateness of %s",
+			job.Name, outcome, now.Sub(workloadAt).Round(time.Second), instance.Spec.MaxLateness.Duration))
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func withWarmUpExit(pod *corev1.Pod, exitCode int32) *corev1.Pod {
+	pod.Status.InitContainerStatuses = []corev1.ContainerStatus{
+		{
+			Name:  warmupContainerInjectNameUID,
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode}},
+		},
+	}
+	return pod
+}
+
+func TestLateRunDecision(t *testing.T) {
+	workloadAt := time.Date(2020, 1, 29, 12, 30, 0, 0, time.UTC)
+	maxLateness := time.Minute * 10
+
+	scenarios := []struct {
+		name string
+		pod  *corev1.Pod
+		now  time.Time
+		late bool
+		wait time.Duration
+	}{
+		{"warming up before the workload time", &corev1.Pod{}, workloadAt.Add(-time.Minute), false, time.Minute * 11},
+		{"pending within the max lateness", &corev1.Pod{}, workloadAt.Add(time.Minute * 4), false, time.Minute * 6},
+		{"pending past the max lateness", &corev1.Pod{}, workloadAt.Add(time.Hour * 3), true, 0},
+		{"warm-up exited late", withWarmUpExit(&corev1.Pod{}, lateExitCode), workloadAt.Add(time.Minute), true, 0},
+		{"already released", withWarmUpExit(&corev1.Pod{}, 0), workloadAt.Add(time.Hour * 3), false, 0},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			late, wait := lateRunDecision(scenario.pod, workloadAt, maxLateness, scenario.now)
+			require.Equal(t, scenario.late, late)
+			require.Equal(t, scenario.wait, wait)
+		})
+	}
+}
+
+// newLateRun is an hourly run with a max lateness of 10 minutes and the pod and job it created
+func newLateRun(policy pscv1alpha1.LatenessPolicy) (*pscv1alpha1.PreScaledCronJob, *corev1.Pod, *batchv1.Job) {
+	instance := newPreviewPSC("hourly", "30 * * * *", 10, "")
+	instance.Spec.MaxLateness = &metav1.Duration{Duration: time.Minute * 10}
+	instance.Spec.LatenessPolicy = policy
+
+	pod := newPrimedPod("autogen-hourly-1580098800", "autogen-hourly-1580098800-abcde")
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "autogen-hourly-1580098800", Namespace: namespace}}
+
+	return &instance, pod, job
+}
+
+func TestReconcileLateness_SkipDeletesTheJob(t *testing.T) {
+	instance, pod, job := newLateRun("")
+	r := newTestPodReconciler(t, pod, job)
+	ctx := context.Background()
+
+	_, err := r.reconcileLateness(ctx, pod, instance, time.Now().Add(-time.Hour*3))
+	require.NoError(t, err)
+
+	err = r.Get(ctx, types.NamespacedName{Name: job.Name, Namespace: namespace}, &batchv1.Job{})
+	require.True(t, errors.IsNotFound(err))
+}
+
+func TestReconcileLateness_FailSetsTheJobsDeadline(t *testing.T) {
+	instance, pod, job := newLateRun(pscv1alpha1.FailLateRun)
+	r := newTestPodReconciler(t, pod, job)
+	ctx := context.Background()
+
+	_, err := r.reconcileLateness(ctx, pod, instance, time.Now().Add(-time.Hour*3))
+	require.NoError(t, err)
+
+	failed := &batchv1.Job{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: job.Name, Namespace: namespace}, failed))
+	require.Equal(t, lateRunFailed, failed.Annotations[LatenessAnnotation])
+	require.Equal(t, int64(1), *failed.Spec.ActiveDeadlineSeconds)
+}
+
+func TestReconcileLateness_RequeuesAtTheDeadline(t *testing.T) {
+	instance, pod, job := newLateRun(pscv1alpha1.FailLateRun)
+	r := newTestPodReconciler(t, pod, job)
+
+	result, err := r.reconcileLateness(context.Background(), pod, instance, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.True(t, result.RequeueAfter > time.Minute*10 && result.RequeueAfter <= time.Minute*11)
+}
+
+func TestReconcileLateness_WaitsForTheBarrier(t *testing.T) {
+	instance, pod, job := newLateRun("")
+	r := newTestPodReconciler(t, pod, job)
+	instance.Spec.ReleaseMode = pscv1alpha1.BarrierRelease
+	ctx := context.Background()
+	workloadAt := time.Now().Add(-time.Hour * 3)
+
+	// held at the barrier the job is kept however late it is
+	result, err := r.reconcileLateness(ctx, pod, instance, workloadAt)
+	require.NoError(t, err)
+	require.Equal(t, ctrl.Result{}, result)
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: job.Name, Namespace: namespace}, &batchv1.Job{}))
+
+	pod.Annotations = map[string]string{BarrierAnnotation: barrierReleased}
+	_, err = r.reconcileLateness(ctx, pod, instance, workloadAt)
+	require.NoError(t, err)
+	err = r.Get(ctx, types.NamespacedName{Name: job.Name, Namespace: namespace}, &batchv1.Job{})
+	require.True(t, errors.IsNotFound(err))
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// LintSupported means a primer schedule can be generated and nothing looks wrong with it
+	LintSupported = "supported"
+	// LintUnsupported means no primer schedule can be generated
+	LintUnsupported = "unsupported"
+	// LintRisky means a primer schedule can be generated but the prescaling is unlikely to behave as expected
+	LintRisky = "risky"
+
+	// lintSampleRuns is how many upcoming runs are inspected when looking for the shortest interval
+	lintSampleRuns = 100
+)
+
+// LintResult describes whether a schedule can be prescaled by the operator
+type LintResult struct {
+	Status         string `json:"status"`
+	Reason         string `json:"reason,omitempty"`
+	PrimerSchedule string `json:"primerSchedule,omitempty"`
+}
+
+// LintPrimerSchedule tries primer generation the same way the reconciler does and reports how well it will work
+func LintPrimerSchedule(scheduleSpec string, warmupMinutes int, primerSchedule string) LintResult {
+	generated, err := GetPrimerSchedule(scheduleSpec, warmupMinutes, primerSchedule)
+	if err != nil {
+		return LintResult{Status: LintUnsupported, Reason: err.Error()}
+	}
+
+	result := LintResult{Status: LintSupported, PrimerSchedule: generated}
+
+	if primerSchedule == "" && warmupMinutes <= 0 {
+		result.Status = LintRisky
+		result.Reason = "no warm-up time is set so the primer fires at the same time as the workload"
+		return result
+	}
+
+	interval, err := shortestInterval(scheduleSpec)
+	if err != nil {
+		return LintResult{Status: LintUnsupported, Reason: err.Error()}
+	}
+
+	warmup := time.Duration(warmupMinutes) * time.Minute
+	if primerSchedule == "" && interval > 0 && warmup >= interval {
+		result.Status = LintRisky
+		result.Reason = fmt.Sprintf("warm-up of %s is not shorter than the %s interval between runs", warmup, interval)
+	}
+
+	return result
+}
+
+// shortestInterval finds the smallest gap between upcoming runs of a schedule
+func shortestInterval(scheduleSpec string) (time.Duration, error) {
+	times, err := NextFireTimes(scheduleSpec, time.Now(), lintSampleRuns)
+	if err != nil {
+		return 0, err
+	}
+
+	var shortest time.Duration
+	for i := 1; i < len(times); i++ {
+		gap := times[i].Sub(times[i-1])
+		if shortest == 0 || gap < shortest {
+			shortest = gap
+		}
+	}
+
+	return shortest, nil
+}
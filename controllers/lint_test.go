@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintPrimerSchedule(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		schedule       string
+		warmupMinutes  int
+		primerSchedule string
+		expectedStatus string
+		expectedPrimer string
+	}{
+		{"supported schedule", "30 * * 10 *", 5, "", LintSupported, "25 * * 10 *"},
+		{"supported primer schedule", "5/30 * * * *", 0, "*/30 * * * *", LintSupported, "*/30 * * * *"},
+		{"every minute is unsupported", "* * * * *", 5, "", LintUnsupported, ""},
+		{"midnight on a weekday moves to the day before", "0 0 * * 5", 5, "", LintSupported, "55 23 * * 4"},
+		{"midnight on the first of the month is unsupported", "0 0 1 * *", 5, "", LintUnsupported, ""},
+		{"invalid schedule is unsupported", "bananas", 5, "", LintUnsupported, ""},
+		{"no warm-up is risky", "30 * * * *", 0, "", LintRisky, "30 * * * *"},
+		{"warm-up longer than interval is risky", "*/10 * * * *", 15, "", LintRisky, "45,55,5,15,25,35 * * * *"},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			result := LintPrimerSchedule(scenario.schedule, scenario.warmupMinutes, scenario.primerSchedule)
+
+			require.Equal(t, scenario.expectedStatus, result.Status)
+			require.Equal(t, scenario.expectedPrimer, result.PrimerSchedule)
+			if scenario.expectedStatus != LintSupported {
+				require.NotEmpty(t, result.Reason)
+			}
+		})
+	}
+}
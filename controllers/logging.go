@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// ReconcileIDAnnotation is added to the events raised while reconciling and holds the ID of the reconcile,
+	// which is logged as reconcileID so an event can be matched with the log lines around it
+	ReconcileIDAnnotation = "psc.cronprimer.local/reconcile-id"
+
+	// debugLevel is the verbosity of log lines which are only interesting when following a reconcile closely
+	debugLevel = 1
+)
+
+type reconcileIDKey struct{}
+
+// startReconcile gives a reconcile a new ID, returning a context carrying it for the events raised and a logger
+// which adds it to every line
+func startReconcile(log logr.Logger, keysAndValues ...interface{}) (context.Context, logr.Logger) {
+	id := string(uuid.NewUUID())
+	ctx := context.WithValue(context.Background(), reconcileIDKey{}, id)
+	return ctx, log.WithValues(append([]interface{}{"reconcileID", id}, keysAndValues...)...)
+}
+
+// reconcileIDFrom is the ID of the reconcile the context belongs to, empty outside of a reconcile
+func reconcileIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(reconcileIDKey{}).(string)
+	return id
+}
+
+// recordEvent raises an event annotated with the ID of the reconcile raising it
+func recordEvent(ctx context.Context, recorder record.EventRecorder, object runtime.Object, eventtype string, reason string, message string) {
+	recordEventf(ctx, recorder, object, eventtype, reason, "%s", message)
+}
+
+// recordEventf raises a formatted event annotated with the ID of the reconcile raising it
+func recordEventf(ctx context.Context, recorder record.EventRecorder, object runtime.Object, eventtype string, reason string, messageFmt string, args ...interface{}) {
+	id := reconcileIDFrom(ctx)
+	if id == "" {
+		recorder.Eventf(object, eventtype, reason, messageFmt, args...)
+		return
+	}
+	recorder.AnnotatedEventf(object, map[string]string{ReconcileIDAnnotation: id}, eventtype, reason, messageFmt, args...)
+}
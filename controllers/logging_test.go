@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// annotationRecorder keeps the annotations of each event, which the fake recorder drops
+type annotationRecorder struct {
+	messages    []string
+	annotations []map[string]string
+}
+
+func (r *annotationRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, "%s", message)
+}
+
+func (r *annotationRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, messageFmt, args...)
+}
+
+func (r *annotationRecorder) PastEventf(object runtime.Object, timestamp metav1.Time, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, messageFmt, args...)
+}
+
+func (r *annotationRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.messages = append(r.messages, fmt.Sprintf(messageFmt, args...))
+	r.annotations = append(r.annotations, annotations)
+}
+
+func TestRecordEvent_AnnotatesTheReconcileID(t *testing.T) {
+	recorder := &annotationRecorder{}
+	instance := newPreviewPSC("hourly", "30 * * * *", 10, "")
+
+	ctx, _ := startReconcile(ctrl.Log.WithName("test"), "prescaledcronjob", instance.Name)
+	id := reconcileIDFrom(ctx)
+	require.NotEmpty(t, id)
+	recordEvent(ctx, recorder, &instance, corev1.EventTypeNormal, "Test", "100% primed")
+	recordEventf(ctx, recorder, &instance, corev1.EventTypeNormal, "Test", "job %s", "autogen-hourly-1")
+
+	// each reconcile has its own ID
+	otherCtx, _ := startReconcile(ctrl.Log.WithName("test"))
+	require.NotEqual(t, id, reconcileIDFrom(otherCtx))
+
+	// outside of a reconcile events aren't annotated
+	recordEvent(context.Background(), recorder, &instance, corev1.EventTypeNormal, "Test", "unannotated")
+
+	require.Equal(t, []string{"100% primed", "job autogen-hourly-1", "unannotated"}, recorder.messages)
+	require.Equal(t, []map[string]string{{ReconcileIDAnnotation: id}, {ReconcileIDAnnotation: id}, nil}, recorder.annotations)
+}
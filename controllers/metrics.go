@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
@@ -24,45 +26,159 @@ var cronjobCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
 var timingLabels = []string{"prescalecron", "nodepool", "durationtype"}
 
 // Track in buckets from 2 secs up to 60mins over 28 increments
-var timingBuckets = prometheus.ExponentialBuckets(2, 1.32, 28)
-var transitionTimeHistograms = map[string]*prometheus.HistogramVec{
-	timeToSchedule:       timeToScheduleHistogram,
-	timeInitContainerRan: timeInitContainerRanHistogram,
-	timeToStartWorkload:  timeToStartWorkloadHistogram,
-	timeDelayOfWorkload:  timeDelayOfWorkloadHistogram,
+var defaultTimingBuckets = prometheus.ExponentialBuckets(2, 1.32, 28)
+
+var transitionHistogramOpts = map[string]prometheus.HistogramOpts{
+	timeToSchedule: {
+		Name: "prescalecronjoboperator_cronjob_time_to_schedule",
+		Help: "How long did it take to schedule the pod used to execute and instance of the CRONJob in secs",
+	},
+	timeInitContainerRan: {
+		Name: "prescalecronjoboperator_cronjob_time_init_container_ran",
+		Help: "How long did the warmup container run waiting for the cron schedule to trigger in secs",
+	},
+	timeToStartWorkload: {
+		Name: "prescalecronjoboperator_cronjob_time_to_start_workload",
+		Help: "How long did it take to start the real workload after warmup container stopped in secs",
+	},
+	timeDelayOfWorkload: {
+		Name: "prescalecronjoboperator_cronjob_time_delay_of_workload",
+		Help: "How long did after it's scheduled start time did the workload actually start in secs",
+	},
+}
+
+var transitionTimeHistograms = newTransitionTimeHistograms(defaultTimingBuckets)
+
+func newTransitionTimeHistograms(buckets []float64) map[string]*prometheus.HistogramVec {
+	histograms := map[string]*prometheus.HistogramVec{}
+	for transitionName, opts := range transitionHistogramOpts {
+		opts.Buckets = buckets
+		histograms[transitionName] = prometheus.NewHistogramVec(opts, timingLabels)
+	}
+	return histograms
 }
 
-var timeToScheduleHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
-	Name:    "prescalecronjoboperator_cronjob_time_to_schedule",
-	Help:    "How long did it take to schedule the pod used to execute and instance of the CRONJob in secs",
-	Buckets: timingBuckets,
-}, timingLabels)
-
-var timeInitContainerRanHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
-	Name:    "prescalecronjoboperator_cronjob_time_init_container_ran",
-	Help:    "How long did the warmup container run waiting for the cron schedule to trigger in secs",
-	Buckets: timingBuckets,
-}, timingLabels)
-
-var timeToStartWorkloadHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
-	Name:    "prescalecronjoboperator_cronjob_time_to_start_workload",
-	Help:    "How long did it take to start the real workload after warmup container stopped in secs",
-	Buckets: timingBuckets,
-}, timingLabels)
-
-var timeDelayOfWorkloadHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
-	Name:    "prescalecronjoboperator_cronjob_time_delay_of_workload",
-	Help:    "How long did after it's scheduled start time did the workload actually start in secs",
-	Buckets: timingBuckets,
-}, timingLabels)
+var plannedCapacityLabels = []string{"nodepool"}
+
+var plannedCPUGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "prescalecronjoboperator_nodepool_planned_cpu_cores",
+	Help: "Peak CPU cores requested by prescaled runs sharing a window on the nodepool within the planning horizon",
+}, plannedCapacityLabels)
+
+var plannedMemoryGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "prescalecronjoboperator_nodepool_planned_memory_bytes",
+	Help: "Peak memory in bytes requested by prescaled runs sharing a window on the nodepool within the planning horizon",
+}, plannedCapacityLabels)
+
+var plannedPodsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "prescalecronjoboperator_nodepool_planned_pods",
+	Help: "Peak number of pods started by prescaled runs sharing a window on the nodepool within the planning horizon",
+}, plannedCapacityLabels)
+
+var plannedWarmUpGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "prescalecronjoboperator_nodepool_next_warmup_timestamp_seconds",
+	Help: "Unix time the next warm-up starts on the nodepool",
+}, plannedCapacityLabels)
+
+var barrierReleaseCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "prescalecronjoboperator_barrier_release_total",
+	Help: "Number of jobs whose pods were released together, by whether all pods were scheduled or the deadline passed",
+}, []string{"prescalecron", "outcome"})
+
+var barrierReleaseDelayHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "prescalecronjoboperator_barrier_release_delay_seconds",
+	Help:    "How long after the workload time the pods of a job were released together in secs",
+	Buckets: defaultTimingBuckets,
+}, []string{"prescalecron", "outcome"})
+
+var lateRunCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "prescalecronjoboperator_late_run_total",
+	Help: "Number of runs which finished warming up later than their maxLateness, by whether they were skipped or failed",
+}, []string{"prescalecron", "outcome"})
+
+var dryRunDecisionCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "prescalecronjoboperator_dry_run_decision_total",
+	Help: "Number of times the decision on what to do with a cronjob changed while running in dry-run mode, by the action that would be taken",
+}, []string{"prescalecron", "action"})
+
+var reconcileErrorCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "prescalecronjoboperator_reconcile_error_total",
+	Help: "Number of failed prescaledcronjob reconciles, by whether the error was permanent or transient",
+}, []string{"prescalecron", "class"})
+
+var runCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "prescalecronjoboperator_run_total",
+	Help: "Number of prescaled runs whose job finished, by whether it completed or failed",
+}, []string{"prescalecron", "outcome"})
+
+var runDurationHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "prescalecronjoboperator_run_duration_seconds",
+	Help:    "How long prescaled runs took from their workload time until their job finished in secs",
+	Buckets: defaultTimingBuckets,
+}, []string{"prescalecron", "outcome"})
+
+var runRetriesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "prescalecronjoboperator_run_retries_total",
+	Help: "Number of pods of finished prescaled runs which failed and were retried by their job",
+}, []string{"prescalecron"})
+
+var runNodeTimeCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "prescalecronjoboperator_run_node_seconds_total",
+	Help: "Time the pods of finished prescaled runs held their nodes in secs, by whether they were warming up or running the workload",
+}, []string{"prescalecron", "nodepool", "phase"})
+
+var warmUpCostLabels = []string{"prescalecron", "nodepool"}
+
+var warmUpCPUCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "prescalecronjoboperator_warmup_cpu_core_seconds_total",
+	Help: "CPU core seconds requested by primed pods while their warm-up container waited",
+}, warmUpCostLabels)
+
+var warmUpMemoryCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "prescalecronjoboperator_warmup_memory_gb_seconds_total",
+	Help: "Memory GB seconds requested by primed pods while their warm-up container waited",
+}, warmUpCostLabels)
+
+var warmUpCostCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "prescalecronjoboperator_warmup_cost_total",
+	Help: "Price of the resources requested by primed pods while their warm-up container waited, for nodepools with a price",
+}, append(warmUpCostLabels, "currency"))
 
 func init() {
 	// Register custom metrics with the global prometheus registry
 	metrics.Registry.MustRegister(cronjobCounter)
-	metrics.Registry.MustRegister(timeToScheduleHistogram)
-	metrics.Registry.MustRegister(timeInitContainerRanHistogram)
-	metrics.Registry.MustRegister(timeToStartWorkloadHistogram)
-	metrics.Registry.MustRegister(timeDelayOfWorkloadHistogram)
+	for _, histogram := range transitionTimeHistograms {
+		metrics.Registry.MustRegister(histogram)
+	}
+	metrics.Registry.MustRegister(plannedCPUGauge)
+	metrics.Registry.MustRegister(plannedMemoryGauge)
+	metrics.Registry.MustRegister(plannedPodsGauge)
+	metrics.Registry.MustRegister(plannedWarmUpGauge)
+	metrics.Registry.MustRegister(barrierReleaseCounter)
+	metrics.Registry.MustRegister(barrierReleaseDelayHistogram)
+	metrics.Registry.MustRegister(lateRunCounter)
+	metrics.Registry.MustRegister(dryRunDecisionCounter)
+	metrics.Registry.MustRegister(reconcileErrorCounter)
+	metrics.Registry.MustRegister(runCounter)
+	metrics.Registry.MustRegister(runDurationHistogram)
+	metrics.Registry.MustRegister(runRetriesCounter)
+	metrics.Registry.MustRegister(runNodeTimeCounter)
+	metrics.Registry.MustRegister(warmUpCPUCounter)
+	metrics.Registry.MustRegister(warmUpMemoryCounter)
+	metrics.Registry.MustRegister(warmUpCostCounter)
+}
+
+// SetTimingBuckets replaces the buckets of the transition time histograms. It must be called before the
+// manager is started as any timings already tracked are dropped.
+func SetTimingBuckets(buckets []float64) {
+	for _, histogram := range transitionTimeHistograms {
+		metrics.Registry.Unregister(histogram)
+	}
+
+	transitionTimeHistograms = newTransitionTimeHistograms(buckets)
+	for _, histogram := range transitionTimeHistograms {
+		metrics.Registry.MustRegister(histogram)
+	}
 }
 
 // TrackCronAction increments the metric tracking how many CronJobs actions
@@ -76,3 +192,96 @@ func TrackCronAction(action string, success bool) {
 
 	cronjobCounter.WithLabelValues(action, outcome).Inc()
 }
+
+// TrackPlannedCapacity publishes the peak planned demand and the next warm-up of each nodepool
+func TrackPlannedCapacity(demands []CapacityDemand) {
+	peaks := map[string]CapacityDemand{}
+	for _, demand := range demands {
+		peak, exists := peaks[demand.Nodepool]
+		if !exists {
+			peaks[demand.Nodepool] = demand
+			continue
+		}
+
+		if demand.CPU.Cmp(peak.CPU) > 0 {
+			peak.CPU = demand.CPU
+		}
+		if demand.Memory.Cmp(peak.Memory) > 0 {
+			peak.Memory = demand.Memory
+		}
+		if demand.Pods > peak.Pods {
+			peak.Pods = demand.Pods
+		}
+		if demand.WarmFrom.Before(peak.WarmFrom) {
+			peak.WarmFrom = demand.WarmFrom
+		}
+		peaks[demand.Nodepool] = peak
+	}
+
+	// nodepools with nothing planned any more shouldn't keep reporting their last value
+	plannedCPUGauge.Reset()
+	plannedMemoryGauge.Reset()
+	plannedPodsGauge.Reset()
+	plannedWarmUpGauge.Reset()
+
+	for nodepool, peak := range peaks {
+		plannedCPUGauge.WithLabelValues(nodepool).Set(float64(peak.CPU.MilliValue()) / 1000)
+		plannedMemoryGauge.WithLabelValues(nodepool).Set(float64(peak.Memory.Value()))
+		plannedPodsGauge.WithLabelValues(nodepool).Set(float64(peak.Pods))
+		plannedWarmUpGauge.WithLabelValues(nodepool).Set(float64(peak.WarmFrom.Unix()))
+	}
+}
+
+// TrackBarrierRelease records the pods of a job being released together
+func TrackBarrierRelease(prescaledName string, outcome string, delay time.Duration) {
+	barrierReleaseCounter.WithLabelValues(prescaledName, outcome).Inc()
+	barrierReleaseDelayHistogram.WithLabelValues(prescaledName, outcome).Observe(delay.Seconds())
+}
+
+// TrackLateRun records a run being skipped or failed for starting later than its maxLateness
+func TrackLateRun(prescaledName string, outcome string) {
+	lateRunCounter.WithLabelValues(prescaledName, outcome).Inc()
+}
+
+// TrackDryRunDecision records what the operator would do to a cronjob when it isn't allowed to write
+func TrackDryRunDecision(prescaledName string, action string) {
+	dryRunDecisionCounter.WithLabelValues(prescaledName, action).Inc()
+}
+
+// TrackReconcileError records a failed prescaledcronjob reconcile
+func TrackReconcileError(prescaledName string, class string) {
+	reconcileErrorCounter.WithLabelValues(prescaledName, class).Inc()
+}
+
+// TrackRunOutcome records a prescaled run's job finishing
+func TrackRunOutcome(prescaledName string, success bool, duration time.Duration, retries int32) {
+	outcome := successMetric
+	if !success {
+		outcome = failureMetric
+	}
+
+	runCounter.WithLabelValues(prescaledName, outcome).Inc()
+	runDurationHistogram.WithLabelValues(prescaledName, outcome).Observe(duration.Seconds())
+	runRetriesCounter.WithLabelValues(prescaledName).Add(float64(retries))
+}
+
+// TrackRunNodeTime records how long the pods of a finished run held their nodes and how much of it was warm-up
+func TrackRunNodeTime(prescaledName string, nodepool string, warmUp time.Duration, total time.Duration) {
+	workload := total - warmUp
+	if workload < 0 {
+		workload = 0
+	}
+	runNodeTimeCounter.WithLabelValues(prescaledName, nodepool, warmUpPhase).Add(warmUp.Seconds())
+	runNodeTimeCounter.WithLabelValues(prescaledName, nodepool, workloadPhase).Add(workload.Seconds())
+}
+
+// TrackWarmUpReservation records the resources a primed pod held on its nodepool while warming up
+func TrackWarmUpReservation(prescaledName string, nodepool string, reservation WarmUpReservation) {
+	warmUpCPUCounter.WithLabelValues(prescaledName, nodepool).Add(reservation.CPUCoreSeconds)
+	warmUpMemoryCounter.WithLabelValues(prescaledName, nodepool).Add(reservation.MemoryGBSeconds)
+}
+
+// TrackWarmUpCost records the price of the resources a primed pod held while warming up
+func TrackWarmUpCost(prescaledName string, nodepool string, currency string, cost float64) {
+	warmUpCostCounter.WithLabelValues(prescaledName, nodepool, currency).Add(cost)
+}
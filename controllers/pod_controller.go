@@ -10,7 +10,7 @@ import (
 	"github.com/ReneKroon/ttlcache"
 	"github.com/go-logr/logr"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/trace"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -21,7 +21,9 @@ import (
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
@@ -34,6 +36,15 @@ type PodReconciler struct {
 	Log                logr.Logger
 	Recorder           record.EventRecorder
 	InitContainerImage string
+
+	// Pods is the label selected cache the reconciler watches and reads primed pods from
+	Pods *PrimedPodCache
+
+	// DryRun leaves pods and jobs untouched, only metrics and events are produced
+	DryRun bool
+
+	// Tracer records each run as a trace once its workload starts, runs aren't traced when nil
+	Tracer trace.Tracer
 }
 
 const (
@@ -42,12 +53,17 @@ const (
 	timeToStartWorkload  = "timeToStartWorkload"
 	timeDelayOfWorkload  = "timeDelayOfWorkload"
 
+	noNodepool = "noneset"
+
 	scheduledEvent                = "Scheduled"
 	startedInitContainerEvent     = "StartedInitContainer"
 	finishedInitContainerEvent    = "FinishedInitContainer"
 	startedWorkloadContainerEvent = "StartedWorkloadContainer"
 )
 
+// TransitionNames lists the transitions tracked for each primed pod in the order they happen
+var TransitionNames = []string{timeToSchedule, timeInitContainerRan, timeToStartWorkload, timeDelayOfWorkload}
+
 type podTransitionTimes struct {
 	createdAt       *metav1.Time
 	scheduledAt     *corev1.Event
@@ -55,24 +71,24 @@ type podTransitionTimes struct {
 	initFinishedAt  *corev1.Event
 	workloadStartAt *corev1.Event
 
+	// workloadStartedNow is set when the workload started since the pod was last looked at
+	workloadStartedNow bool
+
 	transitionsObserved map[string]time.Duration
 }
 
 // +kubebuilder:rbac:groups="",resources=events,verbs=get;list;patch;watch
-// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;patch
 // +kubebuilder:rbac:groups="",resources=pods/status,verbs=get
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;patch;delete
 
 // Reconcile watches for Pods created as a results of a PrimedCronJob and tracks metrics against the parent
 // PrimedCronJob about the instance by inspecting the events on the pod (for example: late, early, init container runtime)
 func (r *PodReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
-	ctx := context.Background()
-	logger := r.Log.WithValues("pod", req.NamespacedName)
-
-	logger.Info(fmt.Sprintf("Starting reconcile loop for %v", req.NamespacedName))
-	defer logger.Info(fmt.Sprintf("Finish reconcile loop for %v", req.NamespacedName))
+	ctx, logger := startReconcile(r.Log, "pod", req.NamespacedName)
 
 	podInstance := &corev1.Pod{}
-	if err := r.Get(ctx, req.NamespacedName, podInstance); err != nil {
+	if err := r.Pods.Get(ctx, req.NamespacedName, podInstance); err != nil {
 		if errors.IsNotFound(err) {
 			return ctrl.Result{}, nil
 		}
@@ -86,9 +102,62 @@ func (r *PodReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 	if !parentExists {
-		logger.Info("prescaledcronjob no longer exists, likely deleted recently")
+		logger.V(debugLevel).Info("Parent prescaledcronjob no longer exists, likely deleted recently")
 		return ctrl.Result{}, nil
 	}
+	// the job ties the lines of a run together across the pod and job controllers
+	logger = logger.WithValues("prescaledcronjob", prescaledInstance.Name, "job", types.NamespacedName{Name: jobNameFor(podInstance), Namespace: podInstance.Namespace})
+
+	// Pods of a prescaledcronjob with several schedules are handled against the schedule they warm up for
+	prescaledInstance, err = ScheduleInstanceForPod(prescaledInstance, podInstance)
+	if err != nil {
+		logger.Info("Unable to find the schedule of the pod", "reason", err.Error())
+		return ctrl.Result{}, nil
+	}
+
+	// In dry-run mode pods and jobs are left alone, skipping straight to the metrics
+	workloadAt, workloadErr := workloadTimeForPod(podInstance, prescaledInstance)
+	manageRun := !r.DryRun && workloadErr == nil
+
+	// Let the warm-up container know when to release, the sooner this is set the sooner the kubelet passes it on
+	if _, stamped := podInstance.Annotations[WorkloadTimeAnnotation]; !stamped && !r.DryRun {
+		if workloadErr != nil {
+			recordEvent(ctx, r.Recorder, prescaledInstance, corev1.EventTypeWarning, "WorkloadTime", fmt.Sprintf("Unable to work out the workload time of pod %s: %s", podInstance.Name, workloadErr))
+		} else if err := r.stampWorkloadTime(ctx, podInstance, workloadAt); err != nil {
+			logger.Error(err, "Failed to set workload time on pod")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// A run on a day excluded by the prescaledcronjob's calendars doesn't happen
+	if manageRun {
+		skipped, err := r.reconcileExclusion(ctx, podInstance, prescaledInstance, workloadAt)
+		if err != nil {
+			logger.Error(err, "Failed to skip an excluded run")
+			return ctrl.Result{}, err
+		}
+		manageRun = !skipped
+	}
+
+	// In barrier mode the pods of a job are held until they can all start together
+	result := ctrl.Result{}
+	if prescaledInstance.Spec.ReleaseMode == pscv1alpha1.BarrierRelease && manageRun {
+		result, err = r.reconcileBarrier(ctx, podInstance, prescaledInstance, workloadAt)
+		if err != nil {
+			logger.Error(err, "Failed to check the job's barrier")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// A run which can no longer start close enough to its workload time is skipped or failed
+	if manageRun {
+		lateness, err := r.reconcileLateness(ctx, podInstance, prescaledInstance, workloadAt)
+		if err != nil {
+			logger.Error(err, "Failed to check the run's lateness")
+			return ctrl.Result{}, err
+		}
+		result = soonestRequeue(result, lateness)
+	}
 
 	// Lets build some stats
 	eventsOnPodOverLastHour, err := r.clientset.CoreV1().Events(podInstance.Namespace).List(metav1.ListOptions{
@@ -100,7 +169,7 @@ func (r *PodReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	// We don't care about this one it has no events yet
 	if len(eventsOnPodOverLastHour.Items) < 1 {
-		return ctrl.Result{}, nil
+		return result, nil
 	}
 
 	// When we do have some events
@@ -114,32 +183,45 @@ func (r *PodReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	newEventsSinceLastRun := getNewEventsSinceLastRun(podInstance.Name, allEvents)
 
 	// Update last tracked event
-	// Track with a 75min TTL to ensure list doesn't grow forever (events exist for 1 hour by default in k8s added a buffer)
-	trackedEventsByPod.SetWithTTL(podInstance.Name, allEvents[0].UID, time.Minute*75)
+	// Track with a TTL to ensure list doesn't grow forever (events exist for 1 hour by default in k8s added a buffer)
+	trackedEventsByPod.SetWithTTL(podInstance.Name, allEvents[0].UID, currentTunables().EventTrackingTTL)
 
 	// No new events - give up
 	if len(newEventsSinceLastRun) < 1 {
-		return ctrl.Result{}, nil
+		return result, nil
 	}
 
-	// Calculate the timings of transitions between states
-	timings, err := generateTransitionTimingsFromEvents(allEvents, newEventsSinceLastRun, podInstance.CreationTimestamp, prescaledInstance.Spec.CronJob.Spec.Schedule)
+	// Calculate the timings of transitions between states, the delay of the workload is measured against the
+	// time the pod was stamped with rather than the schedule, which can't tell which run a late pod was for.
+	// Failing to work it out has been reported already and leaves the delay out.
+	podWorkloadAt, _ := PodWorkloadTime(podInstance, prescaledInstance)
+	timings, err := generateTransitionTimingsFromEvents(allEvents, newEventsSinceLastRun, podInstance.CreationTimestamp, podWorkloadAt)
 	if err != nil {
 		//generateTransitionTimings errors are only partial faults so can log and continue
 		// worst case this error means a transition time wasn't available
-		r.Recorder.Event(prescaledInstance, corev1.EventTypeWarning, "Metrics", err.Error())
+		recordEvent(ctx, r.Recorder, prescaledInstance, corev1.EventTypeWarning, "Metrics", err.Error())
 	}
 
-	r.publishMetrics(timings, podInstance, prescaledInstance)
+	r.publishMetrics(ctx, timings, podInstance, prescaledInstance)
+	traceRun(r.Tracer, timings, podInstance, prescaledInstance, podWorkloadAt)
+
+	recordEvent(ctx, r.Recorder, prescaledInstance, corev1.EventTypeNormal, "Debug", "Metrics calculated for PrescaleCronJob invocation.")
 
-	r.Recorder.Event(prescaledInstance, corev1.EventTypeNormal, "Debug", "Metrics calculated for PrescaleCronJob invocation.")
+	return result, nil
+}
 
-	return ctrl.Result{}, nil
+// soonestRequeue combines the results of two checks, requeuing for whichever needs to look again first
+func soonestRequeue(a ctrl.Result, b ctrl.Result) ctrl.Result {
+	if a.RequeueAfter == 0 || (b.RequeueAfter != 0 && b.RequeueAfter < a.RequeueAfter) {
+		a.RequeueAfter = b.RequeueAfter
+	}
+	a.Requeue = a.Requeue || b.Requeue
+	return a
 }
 
 func (r *PodReconciler) getParentPrescaledCronIfExists(ctx context.Context, podInstance *corev1.Pod) (exists bool, instance *pscv1alpha1.PreScaledCronJob, err error) {
 	// Attempt to get the parent name from the pod
-	prescaledName, exists := podInstance.GetLabels()[primedCronLabel]
+	prescaledName, exists := podInstance.GetLabels()[PrimedCronLabel]
 	if !exists {
 		return false, nil, nil
 	}
@@ -171,7 +253,9 @@ func getNewEventsSinceLastRun(podName string, latestEventsFirst []corev1.Event)
 	return eventsSinceLastCheck
 }
 
-func generateTransitionTimingsFromEvents(allEvents []corev1.Event, newEventsSinceLastRun map[types.UID]corev1.Event, podCreationTime metav1.Time, cronSchedule string) (podTransitionTimes, error) {
+// generateTransitionTimingsFromEvents works out the transitions of a pod, workloadAt is when its workload was
+// meant to start and is zero when that isn't known
+func generateTransitionTimingsFromEvents(allEvents []corev1.Event, newEventsSinceLastRun map[types.UID]corev1.Event, podCreationTime metav1.Time, workloadAt time.Time) (podTransitionTimes, error) {
 	// What do we know?
 	timings := podTransitionTimes{
 		createdAt:           &podCreationTime,
@@ -212,27 +296,42 @@ func generateTransitionTimingsFromEvents(allEvents []corev1.Event, newEventsSinc
 	}
 
 	if allHaveOccurredWithAtLeastOneNew(newEventsSinceLastRun, timings.workloadStartAt) {
+		timings.workloadStartedNow = true
+
 		// Todo: Track as vectored metric by early/late
-		schedule, err := cron.ParseStandard(cronSchedule)
-		if err != nil {
-			return timings, fmt.Errorf("Parital failure generating transition times, failed to parse CRON Schedule: %s", err.Error())
+		if workloadAt.IsZero() {
+			return timings, fmt.Errorf("Parital failure generating transition times, the workload time of the pod is unknown")
 		}
 
-		expectedStartTimeForWorkload := schedule.Next(podCreationTime.Time)
-		timings.transitionsObserved[timeDelayOfWorkload] = timings.workloadStartAt.LastTimestamp.Time.Sub(expectedStartTimeForWorkload)
+		timings.transitionsObserved[timeDelayOfWorkload] = timings.workloadStartAt.LastTimestamp.Time.Sub(workloadAt)
 	}
 
 	return timings, nil
 }
 
-func (r *PodReconciler) publishMetrics(timings podTransitionTimes, pod *corev1.Pod, prescaledInstance *pscv1alpha1.PreScaledCronJob) {
-	agentpool, exists := pod.Spec.NodeSelector["agentpool"]
-	if !exists {
-		agentpool = "noneset"
+// ObservedTransitions calculates every transition time which can be derived from a pod's events, workloadAt is
+// when its workload was meant to start and is zero when that isn't known
+func ObservedTransitions(events []corev1.Event, pod *corev1.Pod, workloadAt time.Time) (map[string]time.Duration, error) {
+	// match the latest -> oldest ordering the reconciler works with and treat every event as new
+	latestEventsFirst := append([]corev1.Event{}, events...)
+	sort.Slice(latestEventsFirst, func(i, j int) bool {
+		return latestEventsFirst[i].FirstTimestamp.After(latestEventsFirst[j].FirstTimestamp.Time)
+	})
+
+	allEvents := map[types.UID]corev1.Event{}
+	for _, event := range latestEventsFirst {
+		allEvents[event.UID] = event
 	}
 
+	timings, err := generateTransitionTimingsFromEvents(latestEventsFirst, allEvents, pod.CreationTimestamp, workloadAt)
+	return timings.transitionsObserved, err
+}
+
+func (r *PodReconciler) publishMetrics(ctx context.Context, timings podTransitionTimes, pod *corev1.Pod, prescaledInstance *pscv1alpha1.PreScaledCronJob) {
+	agentpool := nodepoolFor(&pod.Spec)
+
 	for transitionName, duration := range timings.transitionsObserved {
-		r.Recorder.Eventf(prescaledInstance, corev1.EventTypeNormal, "Metrics", "Event %s took %s on pod %s", transitionName, duration.String(), pod.Name)
+		recordEventf(ctx, r.Recorder, prescaledInstance, corev1.EventTypeNormal, "Metrics", "Event %s took %s on pod %s", transitionName, duration.String(), pod.Name)
 
 		durationSecs := duration.Seconds()
 
@@ -245,10 +344,24 @@ func (r *PodReconciler) publishMetrics(timings podTransitionTimes, pod *corev1.P
 
 		histogram, exists := transitionTimeHistograms[transitionName]
 		if !exists {
-			r.Recorder.Eventf(prescaledInstance, corev1.EventTypeWarning, "Metrics", "Failed to track transition time as no histogram defined for %s", transitionName)
+			recordEventf(ctx, r.Recorder, prescaledInstance, corev1.EventTypeWarning, "Metrics", "Failed to track transition time as no histogram defined for %s", transitionName)
 		}
 		histogram.With(promLabels).Observe(durationSecs)
 	}
+
+	// the time spent warming up is what prescaling costs
+	if waited, observed := timings.transitionsObserved[timeInitContainerRan]; observed {
+		trackWarmUpCost(pod, prescaledInstance, waited)
+	}
+}
+
+// nodepoolFor returns the nodepool a pod spec is pinned to through its node selector
+func nodepoolFor(podSpec *corev1.PodSpec) string {
+	agentpool, exists := podSpec.NodeSelector[currentTunables().NodepoolLabel]
+	if !exists {
+		return noNodepool
+	}
+	return agentpool
 }
 
 func allHaveOccurredWithAtLeastOneNew(newEvents map[types.UID]corev1.Event, events ...*corev1.Event) bool {
@@ -311,28 +424,42 @@ func getEventType(event corev1.Event) (isInteresting bool, eventType string) {
 
 // SetupWithManager sets up defaults
 func (r *PodReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Pods == nil {
+		return fmt.Errorf("pod reconciler needs a primed pod cache to watch")
+	}
+
 	// Get clientset so we can read events
 	r.clientset = kubernetes.NewForConfigOrDie(mgr.GetConfig())
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Pod{}).
-		WithEventFilter(predicate.Funcs{
-			// We're using the pod controller to watch for the job moving from init -> normal execution
-			// given this we don't care about Delete or Create only update and only update on
-			// pods which are downstream of the CronPrimer object
-			DeleteFunc: func(e event.DeleteEvent) bool {
-				return false
-			},
-			CreateFunc: func(e event.CreateEvent) bool {
+	c, err := controller.New("pod", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// We're using the pod controller to watch for the job moving from init -> normal execution
+	// given this we don't care about Delete or Create only update and only update on
+	// pods which are downstream of the CronPrimer object
+	filter := predicate.Funcs{
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		CreateFunc: func(e event.CreateEvent) bool {
+			return true
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			// Going for an injected pod label instead
+			if _, exists := e.MetaNew.GetLabels()[PrimedCronLabel]; exists {
 				return true
-			},
-			UpdateFunc: func(e event.UpdateEvent) bool {
-				// Going for an injected pod label instead
-				if _, exists := e.MetaNew.GetLabels()[primedCronLabel]; exists {
-					return true
-				}
-				return false
-			},
-		}).
-		Complete(r)
+			}
+			return false
+		},
+	}
+
+	// only primed pods are cached, so the watch doesn't need every pod in the cluster
+	for _, src := range r.Pods.Sources() {
+		if err := c.Watch(src, &handler.EnqueueRequestForObject{}, filter); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -146,7 +146,7 @@ var _ = Describe("Pod Controller", func() {
 		workloadPullEvent := mustReadEventFromFile("../testdata/events/workloadPulledEvent.json")
 		workloadStartedEvent := mustReadEventFromFile("../testdata/events/workloadStartedEvent.json")
 		unintestingEvent := mustReadEventFromFile("../testdata/events/uninterestingEvent.json")
-		cronSchedule := "*/1 * * * *"
+		workloadAt := time.Date(2020, 1, 29, 12, 4, 0, 0, time.UTC)
 
 		It("Should correctly identify initStartedEvent", func() {
 			isInteresting, eventType := getEventType(initStartedSampleEvent)
@@ -199,7 +199,7 @@ var _ = Describe("Pod Controller", func() {
 				panic(err)
 			}
 			creationTime := metav1.NewTime(time)
-			timings, err := generateTransitionTimingsFromEvents(allEvents, newEvents, creationTime, cronSchedule)
+			timings, err := generateTransitionTimingsFromEvents(allEvents, newEvents, creationTime, workloadAt)
 
 			It("Shouldn't error", func() {
 				Expect(err).To(BeNil())
@@ -223,7 +223,7 @@ var _ = Describe("Pod Controller", func() {
 				Expect(timings.transitionsObserved[timeToSchedule].String()).To(Equal("2s"))        // Time between Create and Schedule
 				Expect(timings.transitionsObserved[timeInitContainerRan].String()).To(Equal("55s")) // Time between initStartEvent.json and workloadPullEvent.json
 				Expect(timings.transitionsObserved[timeToStartWorkload].String()).To(Equal("2s"))   // Time between workloadPullEvent.json and worloadStartEvent.json
-				Expect(timings.transitionsObserved[timeDelayOfWorkload].String()).To(Equal("5s"))   // Based on the cron schedule how late was the workload
+				Expect(timings.transitionsObserved[timeDelayOfWorkload].String()).To(Equal("5s"))   // How late the workload was against its workload time
 			})
 
 			It("Should only calculate new transition times", func() {
@@ -231,7 +231,7 @@ var _ = Describe("Pod Controller", func() {
 					workloadPullEvent.UID:    workloadPullEvent,
 					workloadStartedEvent.UID: workloadStartedEvent,
 				}
-				expectedReducedTimings, err := generateTransitionTimingsFromEvents(allEvents, reducedNewEvents, creationTime, cronSchedule)
+				expectedReducedTimings, err := generateTransitionTimingsFromEvents(allEvents, reducedNewEvents, creationTime, workloadAt)
 				Expect(err).To(BeNil())
 
 				_, timeToScheduleExists := expectedReducedTimings.transitionsObserved[timeToSchedule]
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// policyHorizon is how far ahead runs are inspected when checking warm-up lengths and concurrency
+	policyHorizon = time.Hour * 24 * 7
+
+	// operatorMode is the only pre-scaling mode the operator implements today
+	operatorMode = pscv1alpha1.InitContainerMode
+)
+
+// policyViolations checks a prescaledcronjob against every PreScalePolicy that selects its namespace
+func policyViolations(ctx context.Context, c client.Reader, instance *pscv1alpha1.PreScaledCronJob) ([]string, error) {
+	policies := &pscv1alpha1.PreScalePolicyList{}
+	if err := c.List(ctx, policies); err != nil {
+		return nil, err
+	}
+	if len(policies.Items) == 0 {
+		return nil, nil
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: instance.Namespace}, namespace); err != nil {
+		return nil, err
+	}
+
+	// other prescaledcronjobs in the namespace are only needed to check concurrency
+	var counted []pscv1alpha1.PreScaledCronJob
+
+	// apply policies in a stable order so messages don't flap between reconciles
+	sort.Slice(policies.Items, func(i, j int) bool {
+		return policies.Items[i].Name < policies.Items[j].Name
+	})
+
+	violations := []string{}
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		selected, err := policySelectsNamespace(policy, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if !selected {
+			continue
+		}
+
+		if policy.Spec.MaxConcurrentWarmPods != nil && counted == nil {
+			list := &pscv1alpha1.PreScaledCronJobList{}
+			if err := c.List(ctx, list, client.InNamespace(instance.Namespace)); err != nil {
+				return nil, err
+			}
+			counted = countedForConcurrency(list.Items, instance)
+		}
+
+		violations = append(violations, checkPolicy(policy, instance, counted)...)
+	}
+
+	return violations, nil
+}
+
+func policySelectsNamespace(policy *pscv1alpha1.PreScalePolicy, namespace *corev1.Namespace) (bool, error) {
+	if policy.Spec.NamespaceSelector == nil {
+		return true, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(policy.Spec.NamespaceSelector)
+	if err != nil {
+		return false, fmt.Errorf("policy %q has an invalid namespaceSelector: %s", policy.Name, err)
+	}
+
+	return selector.Matches(labels.Set(namespace.Labels)), nil
+}
+
+// checkPolicy returns a message for every limit of the policy the prescaledcronjob breaks, counted holds the
+// prescaledcronjobs its warm pods are counted alongside
+func checkPolicy(policy *pscv1alpha1.PreScalePolicy, instance *pscv1alpha1.PreScaledCronJob, counted []pscv1alpha1.PreScaledCronJob) []string {
+	violations := []string{}
+	now := time.Now()
+
+	if policy.Spec.MaxWarmUpMinutes != nil {
+		maxWarmUp := time.Duration(*policy.Spec.MaxWarmUpMinutes) * time.Minute
+		if warmUp := longestWarmUp(instance, now); warmUp > maxWarmUp {
+			violations = append(violations, fmt.Sprintf("policy %q: warm-up of %s exceeds the maximum of %s", policy.Name, warmUp, maxWarmUp))
+		}
+	}
+
+	if len(policy.Spec.AllowedNodepools) > 0 {
+		nodepool := nodepoolFor(&instance.Spec.CronJob.Spec.JobTemplate.Spec.Template.Spec)
+		if !containsString(policy.Spec.AllowedNodepools, nodepool) {
+			violations = append(violations, fmt.Sprintf("policy %q: nodepool %s is not allowed", policy.Name, nodepool))
+		}
+	}
+
+	if len(policy.Spec.AllowedModes) > 0 && !containsMode(policy.Spec.AllowedModes, operatorMode) {
+		violations = append(violations, fmt.Sprintf("policy %q: %s mode is not allowed", policy.Name, operatorMode))
+	}
+
+	if policy.Spec.MaxConcurrentWarmPods != nil {
+		if peak := peakConcurrentWarmPods(counted, now, now.Add(policyHorizon)); peak > int64(*policy.Spec.MaxConcurrentWarmPods) {
+			violations = append(violations, fmt.Sprintf("policy %q: up to %d pods would be warming up at once in namespace %s, the maximum is %d",
+				policy.Name, peak, instance.Namespace, *policy.Spec.MaxConcurrentWarmPods))
+		}
+	}
+
+	return violations
+}
+
+// longestWarmUp is the longest gap between a primer and its workload, which may vary with a custom primer schedule
+func longestWarmUp(instance *pscv1alpha1.PreScaledCronJob, now time.Time) time.Duration {
+	runs, err := UpcomingRuns(instance, now, now.Add(policyHorizon))
+	if err != nil {
+		// invalid schedules are reported when the cronjob is generated
+		return 0
+	}
+
+	var longest time.Duration
+	for _, run := range runs {
+		if warmUp := run.WorkloadAt.Sub(run.PrimerAt); warmUp > longest {
+			longest = warmUp
+		}
+	}
+	return longest
+}
+
+// peakConcurrentWarmPods finds the most pods warming up at the same time between from and until
+func peakConcurrentWarmPods(instances []pscv1alpha1.PreScaledCronJob, from time.Time, until time.Time) int64 {
+	type change struct {
+		at    time.Time
+		delta int64
+	}
+
+	changes := []change{}
+	for i := range instances {
+		runs, err := UpcomingRuns(&instances[i], from, until)
+		if err != nil {
+			continue
+		}
+		pods := podsPerRun(&instances[i])
+		for _, run := range runs {
+			changes = append(changes, change{at: run.PrimerAt, delta: pods}, change{at: run.WorkloadAt, delta: -pods})
+		}
+	}
+
+	// pods released at the same moment another warm-up starts don't overlap
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].at.Equal(changes[j].at) {
+			return changes[i].delta < changes[j].delta
+		}
+		return changes[i].at.Before(changes[j].at)
+	})
+
+	var current, peak int64
+	for _, c := range changes {
+		current += c.delta
+		if current > peak {
+			peak = current
+		}
+	}
+	return peak
+}
+
+// countedForConcurrency is the prescaledcronjob being checked and the older ones in its namespace that are
+// still warming up pods. Only the objects that push the namespace over the limit are reported, so a new
+// prescaledcronjob can't get the ones already running suspended, and suspended ones don't hold the rest back.
+func countedForConcurrency(instances []pscv1alpha1.PreScaledCronJob, instance *pscv1alpha1.PreScaledCronJob) []pscv1alpha1.PreScaledCronJob {
+	result := []pscv1alpha1.PreScaledCronJob{*instance}
+	for i := range instances {
+		item := &instances[i]
+		if item.Name == instance.Name || suspended(item) || !createdBefore(item, instance) {
+			continue
+		}
+		result = append(result, *item)
+	}
+	return result
+}
+
+// suspended is true when none of the prescaledcronjob's cronjobs fire
+func suspended(instance *pscv1alpha1.PreScaledCronJob) bool {
+	if instance.Spec.CronJob.Spec.Suspend != nil && *instance.Spec.CronJob.Spec.Suspend {
+		return true
+	}
+	condition := findCondition(&instance.Status, pscv1alpha1.PolicyViolation)
+	return condition != nil && condition.Status == corev1.ConditionTrue
+}
+
+// createdBefore orders prescaledcronjobs by age, one that hasn't been created yet is the newest
+func createdBefore(a *pscv1alpha1.PreScaledCronJob, b *pscv1alpha1.PreScaledCronJob) bool {
+	switch {
+	case a.CreationTimestamp.IsZero() != b.CreationTimestamp.IsZero():
+		return b.CreationTimestamp.IsZero()
+	case !a.CreationTimestamp.Equal(&b.CreationTimestamp):
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	default:
+		return a.Name < b.Name
+	}
+}
+
+func containsMode(modes []pscv1alpha1.PreScaleMode, mode pscv1alpha1.PreScaleMode) bool {
+	for _, item := range modes {
+		if item == mode {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func newTestPolicy(name string, tenantOnly bool) *pscv1alpha1.PreScalePolicy {
+	maxWarmUp := 10
+	policy := &pscv1alpha1.PreScalePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: pscv1alpha1.PreScalePolicySpec{
+			MaxWarmUpMinutes: &maxWarmUp,
+			AllowedNodepools: []string{"cpu"},
+		},
+	}
+	if tenantOnly {
+		policy.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}}
+	}
+	return policy
+}
+
+func newTestNamespace(tenant bool) *corev1.Namespace {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+	if tenant {
+		namespace.Labels = map[string]string{"tenant": "true"}
+	}
+	return namespace
+}
+
+func TestPolicyViolations_ReportsEachBrokenLimit(t *testing.T) {
+	instance := newPreviewPSC("too-long", "30 * * * *", 20, "gpu")
+	r := newTestReconciler(t, newTestNamespace(true), newTestPolicy("tenant-limits", true))
+
+	violations, err := policyViolations(context.Background(), r.Client, &instance)
+
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`policy "tenant-limits": warm-up of 20m0s exceeds the maximum of 10m0s`,
+		`policy "tenant-limits": nodepool gpu is not allowed`,
+	}, violations)
+}
+
+func TestPolicyViolations_IgnoresUnselectedNamespaces(t *testing.T) {
+	instance := newPreviewPSC("too-long", "30 * * * *", 20, "gpu")
+	r := newTestReconciler(t, newTestNamespace(false), newTestPolicy("tenant-limits", true))
+
+	violations, err := policyViolations(context.Background(), r.Client, &instance)
+
+	require.NoError(t, err)
+	require.Empty(t, violations)
+}
+
+func TestPolicyViolations_ChecksModesAndConcurrency(t *testing.T) {
+	maxPods := int32(2)
+	policy := &pscv1alpha1.PreScalePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "strict"},
+		Spec: pscv1alpha1.PreScalePolicySpec{
+			MaxConcurrentWarmPods: &maxPods,
+			AllowedModes:          []pscv1alpha1.PreScaleMode{pscv1alpha1.InitContainerMode},
+		},
+	}
+	first := newPreviewPSC("first", "0 0 * * *", 15, "")
+	second := newPreviewPSC("second", "5 0 * * *", 15, "")
+	instance := newPreviewPSC("third", "0 0 * * *", 5, "")
+	r := newTestReconciler(t, newTestNamespace(false), policy, &first, &second)
+
+	violations, err := policyViolations(context.Background(), r.Client, &instance)
+
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`policy "strict": up to 3 pods would be warming up at once in namespace psc-system, the maximum is 2`,
+	}, violations)
+}
+
+func TestPolicyViolations_ConcurrencyOnlyReportsTheNewest(t *testing.T) {
+	maxPods := int32(2)
+	policy := &pscv1alpha1.PreScalePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "strict"},
+		Spec:       pscv1alpha1.PreScalePolicySpec{MaxConcurrentWarmPods: &maxPods},
+	}
+	createdAt := time.Date(2020, 1, 27, 12, 0, 0, 0, time.UTC)
+	first := newPreviewPSC("first", "0 0 * * *", 15, "")
+	first.CreationTimestamp = metav1.NewTime(createdAt)
+	second := newPreviewPSC("second", "5 0 * * *", 15, "")
+	second.CreationTimestamp = metav1.NewTime(createdAt.Add(time.Hour))
+	newest := newPreviewPSC("a-newest", "0 0 * * *", 5, "")
+	newest.CreationTimestamp = metav1.NewTime(createdAt.Add(time.Hour * 2))
+	r := newTestReconciler(t, newTestNamespace(false), policy, &first, &second, &newest)
+	ctx := context.Background()
+
+	// the objects that were already running stay within the policy
+	for _, instance := range []*pscv1alpha1.PreScaledCronJob{&first, &second} {
+		violations, err := policyViolations(ctx, r.Client, instance)
+		require.NoError(t, err)
+		require.Empty(t, violations, instance.Name)
+	}
+
+	violations, err := policyViolations(ctx, r.Client, &newest)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`policy "strict": up to 3 pods would be warming up at once in namespace psc-system, the maximum is 2`,
+	}, violations)
+}
+
+func TestPolicyViolations_ConcurrencyIgnoresSuspendedObjects(t *testing.T) {
+	maxPods := int32(2)
+	policy := &pscv1alpha1.PreScalePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "strict"},
+		Spec:       pscv1alpha1.PreScalePolicySpec{MaxConcurrentWarmPods: &maxPods},
+	}
+	suspend := true
+	paused := newPreviewPSC("paused", "0 0 * * *", 15, "")
+	paused.Spec.CronJob.Spec.Suspend = &suspend
+	violating := newPreviewPSC("violating", "0 0 * * *", 15, "")
+	setCondition(&violating.Status, pscv1alpha1.PolicyViolation, corev1.ConditionTrue, "PolicyViolated", "")
+	running := newPreviewPSC("running", "5 0 * * *", 15, "")
+	instance := newPreviewPSC("third", "0 0 * * *", 5, "")
+	r := newTestReconciler(t, newTestNamespace(false), policy, &paused, &violating, &running)
+
+	violations, err := policyViolations(context.Background(), r.Client, &instance)
+
+	require.NoError(t, err)
+	require.Empty(t, violations)
+}
+
+func TestReconcile_PolicyViolationSuspendsCronAndSetsCondition(t *testing.T) {
+	instance := newPreviewPSC("too-long", "30 * * * *", 20, "gpu")
+	instance.Finalizers = []string{finalizerName}
+	r := newTestReconciler(t, newTestNamespace(true), newTestPolicy("tenant-limits", true), &instance)
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	ctx := context.Background()
+
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	fetched := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(ctx, key, fetched))
+	condition := findCondition(&fetched.Status, pscv1alpha1.PolicyViolation)
+	require.NotNil(t, condition)
+	require.Equal(t, corev1.ConditionTrue, condition.Status)
+
+	cron := &batchv1beta1.CronJob{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: autogenName(&instance), Namespace: instance.Namespace}, cron))
+	require.True(t, *cron.Spec.Suspend)
+}
+
+func TestValidator_DeniesPolicyViolations(t *testing.T) {
+	testScheme := newTestScheme(t)
+	r := newTestReconciler(t, newTestNamespace(true), newTestPolicy("tenant-limits", true))
+	decoder, err := admission.NewDecoder(testScheme)
+	require.NoError(t, err)
+	validator := &PreScaledCronJobValidator{Client: r.Client}
+	require.NoError(t, validator.InjectDecoder(decoder))
+
+	scenarios := []struct {
+		name     string
+		instance pscv1alpha1.PreScaledCronJob
+		allowed  bool
+	}{
+		{"within policy", newPreviewPSC("ok", "30 * * * *", 5, "cpu"), true},
+		{"breaks policy", newPreviewPSC("too-long", "30 * * * *", 20, "cpu"), false},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			raw, err := json.Marshal(scenario.instance)
+			require.NoError(t, err)
+
+			response := validator.Handle(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1beta1.AdmissionRequest{
+					Namespace: namespace,
+					Operation: admissionv1beta1.Create,
+					Object:    runtime.RawExtension{Raw: raw},
+				},
+			})
+
+			require.Equal(t, scenario.allowed, response.Allowed)
+		})
+	}
+}
+
+func TestValidator_AllowsCleanupOfObjectsBreakingPolicy(t *testing.T) {
+	r := newTestReconciler(t, newTestNamespace(true), newTestPolicy("tenant-limits", true))
+	decoder, err := admission.NewDecoder(newTestScheme(t))
+	require.NoError(t, err)
+	validator := &PreScaledCronJobValidator{Client: r.Client}
+	require.NoError(t, validator.InjectDecoder(decoder))
+
+	// stored before the policy was created, it now breaks it
+	old := newPreviewPSC("too-long", "30 * * * *", 20, "cpu")
+	old.Finalizers = []string{finalizerName}
+
+	withoutFinalizer := old.DeepCopy()
+	withoutFinalizer.Finalizers = nil
+
+	deleting := old.DeepCopy()
+	deletedAt := metav1.Now()
+	deleting.DeletionTimestamp = &deletedAt
+	deleting.Spec.WarmUpTimeMins = 30
+
+	longerWarmUp := old.DeepCopy()
+	longerWarmUp.Spec.WarmUpTimeMins = 30
+
+	scenarios := []struct {
+		name     string
+		instance *pscv1alpha1.PreScaledCronJob
+		allowed  bool
+	}{
+		{"finalizer removed", withoutFinalizer, true},
+		{"being deleted", deleting, true},
+		{"spec changed", longerWarmUp, false},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			oldRaw, err := json.Marshal(old)
+			require.NoError(t, err)
+			raw, err := json.Marshal(scenario.instance)
+			require.NoError(t, err)
+
+			response := validator.Handle(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1beta1.AdmissionRequest{
+					Namespace: namespace,
+					Operation: admissionv1beta1.Update,
+					Object:    runtime.RawExtension{Raw: raw},
+					OldObject: runtime.RawExtension{Raw: oldRaw},
+				},
+			})
+
+			require.Equal(t, scenario.allowed, response.Allowed)
+		})
+	}
+}
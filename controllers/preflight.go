@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/go-logr/logr"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// preflightLead is how long before the primer fires the prescaledcronjob is checked again
+const preflightLead = time.Minute
+
+// preflightRequeue is how long until shortly before the primer next fires, skipping a fire too close to check for
+func preflightRequeue(primerSchedule string, now time.Time) (time.Duration, error) {
+	fireTimes, err := NextFireTimes(primerSchedule, now, 2)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, fireAt := range fireTimes {
+		if wait := fireAt.Add(-preflightLead).Sub(now); wait > 0 {
+			return wait, nil
+		}
+	}
+	return 0, nil
+}
+
+// preflightProblems checks the autogenerated cronjob will fire as generated and its pods have a nodepool to land on
+func (r *PreScaledCronJobReconciler) preflightProblems(ctx context.Context, instance *pscv1alpha1.PreScaledCronJob,
+	cron *batchv1beta1.CronJob, objectHash string, suspendExpected bool) ([]string, error) {
+
+	problems := []string{}
+	switch {
+	case cron == nil:
+		problems = append(problems, fmt.Sprintf("cronjob %s does not exist", autogenName(instance)))
+	case !metav1.IsControlledBy(cron, instance):
+		problems = append(problems, fmt.Sprintf("cronjob %s is not owned by this prescaledcronjob", cron.Name))
+	default:
+		if cron.Spec.Suspend != nil && *cron.Spec.Suspend && !suspendExpected {
+			problems = append(problems, fmt.Sprintf("cronjob %s is suspended", cron.Name))
+		}
+		if hash := cron.Annotations[objectHashField]; hash != objectHash {
+			problems = append(problems, fmt.Sprintf("cronjob %s has hash %s, expected %s", cron.Name, hash, objectHash))
+		}
+	}
+
+	nodepool := nodepoolFor(&instance.Spec.CronJob.Spec.JobTemplate.Spec.Template.Spec)
+	if nodepool != noNodepool {
+		nodes := &corev1.NodeList{}
+		if err := r.policyReader().List(ctx, nodes, client.MatchingLabels{currentTunables().NodepoolLabel: nodepool}); err != nil {
+			return nil, err
+		}
+		if len(nodes.Items) == 0 {
+			problems = append(problems, fmt.Sprintf("nodepool %s has no nodes", nodepool))
+		}
+	}
+
+	return problems, nil
+}
+
+// preflight records the outcome of the checks on each cronjob in the PreflightFailed condition and requeues the
+// prescaledcronjob to run them again shortly before one of its primers next fires
+func (r *PreScaledCronJobReconciler) preflight(ctx context.Context, instance *pscv1alpha1.PreScaledCronJob, crons []primerCronJob, logger logr.Logger) (ctrl.Result, error) {
+	problems := []string{}
+	var wait time.Duration
+	now := time.Now()
+	for _, cron := range crons {
+		cronProblems, err := r.preflightProblems(ctx, cron.instance, cron.existing, cron.objectHash, cron.suspendExpected)
+		if err != nil {
+			logger.Error(err, "Failed to run preflight checks")
+			return ctrl.Result{}, err
+		}
+		// the nodepool is shared by every schedule, so report it once
+		for _, problem := range cronProblems {
+			if !containsString(problems, problem) {
+				problems = append(problems, problem)
+			}
+		}
+
+		primerSchedule, err := PrimerScheduleFor(cron.instance)
+		if err != nil {
+			return ctrl.Result{}, permanentError(err)
+		}
+		cronWait, err := preflightRequeue(primerSchedule, now)
+		if err != nil {
+			return ctrl.Result{}, permanentError(err)
+		}
+		if wait == 0 || (cronWait > 0 && cronWait < wait) {
+			wait = cronWait
+		}
+	}
+
+	if err := r.updatePreflightCondition(ctx, instance, problems); err != nil {
+		logger.Error(err, "Failed to update status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: wait}, nil
+}
+
+// updatePreflightCondition records any preflight problems in status and raises an event when they're first seen
+func (r *PreScaledCronJobReconciler) updatePreflightCondition(ctx context.Context, instance *pscv1alpha1.PreScaledCronJob, problems []string) error {
+	changed := false
+	if len(problems) > 0 {
+		message := strings.Join(problems, "; ")
+		changed = setCondition(&instance.Status, pscv1alpha1.PreflightFailed, corev1.ConditionTrue, "PreflightFailed", message)
+		if changed {
+			recordEvent(ctx, r.Recorder, instance, corev1.EventTypeWarning, "Preflight failed", message)
+		}
+	} else if findCondition(&instance.Status, pscv1alpha1.PreflightFailed) != nil {
+		changed = setCondition(&instance.Status, pscv1alpha1.PreflightFailed, corev1.ConditionFalse, "PreflightPassed", "")
+	}
+
+	if !changed {
+		return nil
+	}
+	return r.Status().Update(ctx, instance)
+}
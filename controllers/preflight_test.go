@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestPreflightRequeue(t *testing.T) {
+	now := time.Date(2020, 1, 29, 12, 0, 0, 0, time.UTC)
+
+	// the primer fires at 12:20, so check at 12:19
+	wait, err := preflightRequeue("20 * * * *", now)
+	require.NoError(t, err)
+	require.Equal(t, time.Minute*19, wait)
+
+	// too close to the next fire to check for it, so check before the one after
+	wait, err = preflightRequeue("20 * * * *", now.Add(time.Minute*19+time.Second*30))
+	require.NoError(t, err)
+	require.Equal(t, time.Minute*59+time.Second*30, wait)
+}
+
+func TestPreflightProblems(t *testing.T) {
+	suspend := true
+	instance := generatePSCSpec()
+	instance.UID = types.UID("psc-uid")
+	instance.Spec.CronJob.Spec.JobTemplate.Spec.Template.Spec.NodeSelector = map[string]string{defaultNodepoolLabel: "gpu"}
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-0", Labels: map[string]string{defaultNodepoolLabel: "gpu"}}}
+	r := newTestReconciler(t, node)
+	cron, err := r.generateCronJob(&instance)
+	require.NoError(t, err)
+	cron.Annotations = map[string]string{objectHashField: "current"}
+	name := cron.Name
+	ctx := context.Background()
+
+	problems, err := r.preflightProblems(ctx, &instance, cron, "current", false)
+	require.NoError(t, err)
+	require.Empty(t, problems)
+
+	problems, err = r.preflightProblems(ctx, &instance, nil, "current", false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"cronjob " + name + " does not exist"}, problems)
+
+	cron.Spec.Suspend = &suspend
+	problems, err = r.preflightProblems(ctx, &instance, cron, "changed", false)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"cronjob " + name + " is suspended",
+		"cronjob " + name + " has hash current, expected changed",
+	}, problems)
+
+	// suspended by a policy violation
+	problems, err = r.preflightProblems(ctx, &instance, cron, "current", true)
+	require.NoError(t, err)
+	require.Empty(t, problems)
+
+	instance.Spec.CronJob.Spec.JobTemplate.Spec.Template.Spec.NodeSelector[defaultNodepoolLabel] = "highmem"
+	problems, err = r.preflightProblems(ctx, &instance, cron, "current", true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"nodepool highmem has no nodes"}, problems)
+}
+
+func TestReconcile_RecordsPreflightAndRequeuesBeforeThePrimerFires(t *testing.T) {
+	instance := generatePSCSpec()
+	instance.Finalizers = []string{finalizerName}
+	instance.Spec.CronJob.Spec.JobTemplate.Spec.Template.Spec.NodeSelector = map[string]string{defaultNodepoolLabel: "gpu"}
+	r := newTestReconciler(t, &instance)
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+
+	result, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+	require.True(t, result.RequeueAfter > 0)
+
+	fetched := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(context.Background(), key, fetched))
+	condition := findCondition(&fetched.Status, pscv1alpha1.PreflightFailed)
+	require.NotNil(t, condition)
+	require.Equal(t, corev1.ConditionTrue, condition.Status)
+	require.Equal(t, "nodepool gpu has no nodes", condition.Message)
+}
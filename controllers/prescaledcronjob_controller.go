@@ -3,15 +3,25 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	pscv1alpha1 "cronprimer.local/api/v1alpha1"
 )
@@ -19,33 +29,56 @@ import (
 // PreScaledCronJobReconciler reconciles a PreScaledCronJob object
 type PreScaledCronJobReconciler struct {
 	client.Client
+	Scheme             *runtime.Scheme
 	Log                logr.Logger
 	Recorder           record.EventRecorder
 	InitContainerImage string
+
+	// WarmUpContainer holds the operator wide defaults of the warm-up container, an empty image uses InitContainerImage
+	WarmUpContainer pscv1alpha1.WarmUpContainerTemplate
+	// WarmUpImagePullSecrets are added to primed pods so the warm-up image can be pulled from a private registry
+	WarmUpImagePullSecrets []corev1.LocalObjectReference
+
+	// Pods reads the warm-up pods, falling back to Client when not set
+	Pods client.Reader
+	// PolicyReader reads the cluster scoped PreScalePolicies, Namespaces and Nodes when the manager's cache is
+	// limited to a set of namespaces. When set, policies are read on each reconcile rather than cached and watched.
+	PolicyReader client.Reader
+
+	// DryRun reports what would be done to the autogenerated cronjob through events, status and metrics
+	// without creating, updating or deleting anything
+	DryRun bool
 }
 
 // +kubebuilder:rbac:groups=psc.cronprimer.local,resources=prescaledcronjobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=psc.cronprimer.local,resources=prescaledcronjobs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=psc.cronprimer.local,resources=prescalepolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=psc.cronprimer.local,resources=exclusioncalendars,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
 // +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=batch,resources=cronjobs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;delete
 // +kubebuilder:rbac:groups="",resources=events,verbs=patch
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list
 // +kubebuilder:rbac:groups="",resources=pods/status,verbs=get
 
 const (
-	objectHashField              = "pscObjectHash"
-	finalizerName                = "foregroundDeletion"
-	primedCronLabel              = "primedcron"
+	objectHashField     = "pscObjectHash"
+	finalizerName       = "psc.cronprimer.local/finalizer"
+	legacyFinalizerName = "foregroundDeletion"
+	// PrimedCronLabel is added to primed pods and holds the name of the prescaledcronjob they belong to
+	PrimedCronLabel              = "primedcron"
 	warmupContainerInjectNameUID = "injected-0d825b4f-07f0-4952-8150-fba894c613b1"
+	autogenPrefix                = "autogen-"
+
+	// childCleanupInterval is how often we check back on children still being removed during deletion
+	childCleanupInterval = time.Second * 5
 )
 
 // Reconcile takes the PreScaled request and creates a regular cron, n mins earlier.
 func (r *PreScaledCronJobReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
-	ctx := context.Background()
-	logger := r.Log.WithValues("prescaledcronjob", req.NamespacedName)
-
-	logger.Info(fmt.Sprintf("Starting reconcile loop for %v", req.NamespacedName))
-	defer logger.Info(fmt.Sprintf("Finish reconcile loop for %v", req.NamespacedName))
+	ctx, logger := startReconcile(r.Log, "prescaledcronjob", req.NamespacedName)
 
 	// instance = the submitted prescaledcronjob CRD
 	instance := &pscv1alpha1.PreScaledCronJob{}
@@ -57,51 +90,153 @@ func (r *PreScaledCronJobReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 		return ctrl.Result{}, err
 	}
 
-	// allow cascade delete of child resources - "foregroundDeletion" tells k8s we want children cleaned up
-	if instance.ObjectMeta.DeletionTimestamp.IsZero() {
-		if !containsString(instance.ObjectMeta.Finalizers, finalizerName) {
-			logger.Info(fmt.Sprintf("AddFinalizer for %v", req.NamespacedName))
-			instance.ObjectMeta.Finalizers = append(instance.ObjectMeta.Finalizers, finalizerName)
-			if err := r.Update(ctx, instance); err != nil {
-				r.Recorder.Event(instance, corev1.EventTypeWarning, "Adding finalizer", fmt.Sprintf("Failed to add finalizer: %s", err))
-				TrackCronAction(CronJobDeletedMetric, false)
-				return ctrl.Result{}, err
+	result, err := r.reconcile(ctx, instance, logger)
+	return r.handleReconcileError(ctx, instance, result, err, logger)
+}
+
+// reconcile brings the autogenerated cronjob in line with the prescaledcronjob, errors which will never succeed
+// without the object changing are wrapped with permanentError
+func (r *PreScaledCronJobReconciler) reconcile(ctx context.Context, instance *pscv1alpha1.PreScaledCronJob, logger logr.Logger) (ctrl.Result, error) {
+	// hold on to the object until its cronjob, jobs and warm-up pods have been cleaned up
+	if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
+		if r.DryRun {
+			if !containsString(instance.ObjectMeta.Finalizers, finalizerName) {
+				return ctrl.Result{}, nil
 			}
-			r.Recorder.Event(instance, corev1.EventTypeNormal, "Adding finalizer", "Object finalizer is added")
-			TrackCronAction(CronJobDeletedMetric, true)
-			return ctrl.Result{}, nil
+			return r.dryRunDelete(ctx, instance, logger)
 		}
+		return r.finalize(ctx, instance, logger)
 	}
 
-	// Generate the cron we'll post and get a hash for it
-	cronToPost, cronGenErr := r.generateCronJob(instance)
-	if cronGenErr != nil {
-		r.Recorder.Event(instance, corev1.EventTypeWarning, "Invalid cron schedule", fmt.Sprintf("Failed to generate cronjob: %s", cronGenErr))
-		logger.Error(cronGenErr, "Failed to generate cronjob")
+	// objects created by earlier versions carry the builtin "foregroundDeletion" finalizer, swap it for ours
+	if !r.DryRun && (!containsString(instance.ObjectMeta.Finalizers, finalizerName) || containsString(instance.ObjectMeta.Finalizers, legacyFinalizerName)) {
+		logger.Info("Adding finalizer", "finalizer", finalizerName)
+		instance.ObjectMeta.Finalizers = removeString(instance.ObjectMeta.Finalizers, legacyFinalizerName)
+		if !containsString(instance.ObjectMeta.Finalizers, finalizerName) {
+			instance.ObjectMeta.Finalizers = append(instance.ObjectMeta.Finalizers, finalizerName)
+		}
+		if err := r.Update(ctx, instance); err != nil {
+			recordEvent(ctx, r.Recorder, instance, corev1.EventTypeWarning, "Adding finalizer", fmt.Sprintf("Failed to add finalizer: %s", err))
+			return ctrl.Result{}, err
+		}
+		recordEvent(ctx, r.Recorder, instance, corev1.EventTypeNormal, "Adding finalizer", "Object finalizer is added")
 		return ctrl.Result{}, nil
 	}
 
-	objectHash, err := Hash(cronToPost, 1)
+	if err := validateSchedules(instance); err != nil {
+		recordEvent(ctx, r.Recorder, instance, corev1.EventTypeWarning, "Invalid cron schedule", err.Error())
+		return ctrl.Result{}, permanentError(err)
+	}
+
+	// Check the object against any PreScalePolicies that apply to its namespace
+	violations, err := policyViolations(ctx, r.policyReader(), instance)
 	if err != nil {
-		logger.Error(err, "Failed to hash cronjob")
+		logger.Error(err, "Failed to evaluate prescale policies")
+		return ctrl.Result{}, err
+	}
+	if err := r.updatePolicyCondition(ctx, instance, violations); err != nil {
+		logger.Error(err, "Failed to update status")
 		return ctrl.Result{}, err
 	}
 
-	existingCron, err := r.getCronJob(ctx, cronToPost.Name, cronToPost.Namespace)
+	if err := r.updatePrimerOffset(ctx, instance); err != nil {
+		logger.Error(err, "Failed to update status")
+		return ctrl.Result{}, err
+	}
 
+	// Runs on the days of the exclusion calendars are skipped by suspending the cronjobs around them
+	excluded, err := r.reconcileExclusions(ctx, instance)
 	if err != nil {
-		// did we get an error because the cronjob doesn't exist?
-		if errors.IsNotFound(err) {
-			return r.createCronJob(ctx, cronToPost, objectHash, instance, logger)
+		logger.Error(err, "Failed to check exclusion calendars")
+		return ctrl.Result{}, err
+	}
+
+	// Generate a cron for each schedule and get a hash for it
+	crons := []primerCronJob{}
+	for _, view := range ScheduleInstances(instance) {
+		cronToPost, cronGenErr := r.generateCronJob(view)
+		if cronGenErr != nil {
+			recordEvent(ctx, r.Recorder, instance, corev1.EventTypeWarning, "Invalid cron schedule", fmt.Sprintf("Failed to generate cronjob: %s", cronGenErr))
+			logger.Error(cronGenErr, "Failed to generate cronjob")
+			return ctrl.Result{}, permanentError(cronGenErr)
+		}
+
+		// keep the cron around but stop it from firing until the policy is satisfied or the excluded days pass
+		if len(violations) > 0 || excluded[ScheduleName(view)] {
+			suspend := true
+			cronToPost.Spec.Suspend = &suspend
+		}
+
+		objectHash, err := Hash(cronToPost, 1)
+		if err != nil {
+			logger.Error(err, "Failed to hash cronjob")
+			return ctrl.Result{}, permanentError(err)
+		}
+
+		crons = append(crons, primerCronJob{
+			instance:        view,
+			generated:       cronToPost,
+			objectHash:      objectHash,
+			suspendExpected: len(violations) > 0 || excluded[ScheduleName(view)] || (instance.Spec.CronJob.Spec.Suspend != nil && *instance.Spec.CronJob.Spec.Suspend),
+		})
+	}
+
+	if r.DryRun {
+		return r.dryRun(ctx, instance, crons, logger)
+	}
+
+	// the operator is no longer in dry-run mode, so the last decision is stale
+	if instance.Status.DryRun != nil {
+		instance.Status.DryRun = nil
+		if err := r.Status().Update(ctx, instance); err != nil {
+			logger.Error(err, "Failed to update status")
+			return ctrl.Result{}, err
 		}
+	}
 
-		// we hit an unexpected problem getting the cron, fail the reconcile loop
-		logger.Error(err, "Failed to get associated cronjob")
+	for i := range crons {
+		cronToPost, objectHash := crons[i].generated, crons[i].objectHash
+		existingCron, err := r.getCronJob(ctx, cronToPost.Name, cronToPost.Namespace)
+		if err != nil {
+			// did we get an error because the cronjob doesn't exist?
+			if !errors.IsNotFound(err) {
+				// we hit an unexpected problem getting the cron, fail the reconcile loop
+				logger.Error(err, "Failed to get associated cronjob")
+				return ctrl.Result{}, err
+			}
+
+			if result, err := r.createCronJob(ctx, cronToPost, objectHash, instance, logger); err != nil {
+				return result, err
+			}
+			existingCron = cronToPost
+		} else if result, err := r.updateCronJob(ctx, existingCron, cronToPost, objectHash, instance, logger); err != nil {
+			// we found a CronJob, lets update it
+			return result, err
+		}
+		crons[i].existing = existingCron
+	}
+
+	// crons of schedules which have been removed, or renamed, go along with their jobs
+	if err := r.deleteStaleCronJobs(ctx, instance, crons); err != nil {
+		recordEvent(ctx, r.Recorder, instance, corev1.EventTypeWarning, "Deleting cronjob", fmt.Sprintf("Failed to delete cronjob of a removed schedule: %s", err))
+		logger.Error(err, "Failed to delete stale cronjobs")
 		return ctrl.Result{}, err
 	}
 
-	// we found a CronJob, lets update it
-	return r.updateCronJob(ctx, existingCron, cronToPost, objectHash, instance, logger)
+	// check the cronjobs are ready to fire and look again shortly before one next does
+	return r.preflight(ctx, instance, crons, logger)
+}
+
+// primerCronJob is a cronjob generated for one of the schedules of a prescaledcronjob
+type primerCronJob struct {
+	// instance is the prescaledcronjob as seen by the schedule
+	instance   *pscv1alpha1.PreScaledCronJob
+	generated  *batchv1beta1.CronJob
+	objectHash string
+	// existing is the cronjob in the cluster, nil when it doesn't exist
+	existing *batchv1beta1.CronJob
+	// suspendExpected is set when the cronjob is meant to be suspended
+	suspendExpected bool
 }
 
 func (r *PreScaledCronJobReconciler) generateCronJob(instance *pscv1alpha1.PreScaledCronJob) (*batchv1beta1.CronJob, error) {
@@ -109,20 +244,23 @@ func (r *PreScaledCronJobReconciler) generateCronJob(instance *pscv1alpha1.PreSc
 	cronToPost := instance.Spec.CronJob.DeepCopy()
 	// add a label so we can watch the pods for metrics generation
 	if cronToPost.Spec.JobTemplate.Spec.Template.ObjectMeta.Labels != nil {
-		cronToPost.Spec.JobTemplate.Spec.Template.ObjectMeta.Labels[primedCronLabel] = instance.Name
+		cronToPost.Spec.JobTemplate.Spec.Template.ObjectMeta.Labels[PrimedCronLabel] = instance.Name
 	} else {
 		cronToPost.Spec.JobTemplate.Spec.Template.ObjectMeta.Labels = map[string]string{
-			primedCronLabel: instance.Name,
+			PrimedCronLabel: instance.Name,
 		}
 	}
+	// pods of a prescaledcronjob with several schedules are told apart by the schedule they warm up for
+	scheduleName := ScheduleName(instance)
+	if scheduleName != "" {
+		cronToPost.Spec.JobTemplate.Spec.Template.ObjectMeta.Labels[ScheduleLabel] = scheduleName
+	}
 
 	// get original cron schedule
 	scheduleSpec := instance.Spec.CronJob.Spec.Schedule
-	warmUpTimeMins := instance.Spec.WarmUpTimeMins
-	primerSchedule := instance.Spec.PrimerSchedule
 
 	// Get the new schedule for the cron
-	primerSchedule, err := GetPrimerSchedule(scheduleSpec, warmUpTimeMins, primerSchedule)
+	primerSchedule, err := PrimerScheduleFor(instance)
 
 	if err != nil {
 		return nil, fmt.Errorf("Failed parse primer schedule: %s", err)
@@ -133,9 +271,12 @@ func (r *PreScaledCronJobReconciler) generateCronJob(instance *pscv1alpha1.PreSc
 
 	// Create + Add the init container that runs on the primed cron schedule
 	// and will die on the CRONJOB_SCHEDULE
+	settings := r.warmUpContainerSettings(instance)
 	initContainer := corev1.Container{
-		Name:  warmupContainerInjectNameUID, // The warmup container has UID to allow pod controller to identify it reliably
-		Image: r.InitContainerImage,
+		Name:            warmupContainerInjectNameUID, // The warmup container has UID to allow pod controller to identify it reliably
+		Image:           settings.Image,
+		ImagePullPolicy: settings.ImagePullPolicy,
+		SecurityContext: settings.SecurityContext,
 		Env: []corev1.EnvVar{
 			{
 				Name:  "NAMESPACE",
@@ -145,24 +286,65 @@ func (r *PreScaledCronJobReconciler) generateCronJob(instance *pscv1alpha1.PreSc
 				Name:  "CRONJOB_SCHEDULE",
 				Value: scheduleSpec,
 			},
+			{
+				Name:  "SCHEDULE_NAME",
+				Value: scheduleName,
+			},
+			{
+				Name:  "WORKLOAD_TIME_FILE",
+				Value: path.Join(workloadTimeMountPath, workloadTimeFile),
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      workloadTimeVolume,
+				MountPath: workloadTimeMountPath,
+				ReadOnly:  true,
+			},
 		},
 	}
+	if settings.Resources != nil {
+		initContainer.Resources = *settings.Resources
+	}
 
-	// set the owner reference on the autogenerated job so it's cleaned up with the parent
-	ownerRef := v1.OwnerReference{
-		APIVersion: instance.APIVersion,
-		Kind:       instance.Kind,
-		Name:       instance.Name,
-		UID:        instance.UID,
+	barrier := instance.Spec.ReleaseMode == pscv1alpha1.BarrierRelease
+	if barrier {
+		initContainer.Env = append(initContainer.Env,
+			corev1.EnvVar{Name: "BARRIER_FILE", Value: path.Join(workloadTimeMountPath, barrierFile)},
+			corev1.EnvVar{Name: "BARRIER_TIMEOUT_SECONDS", Value: strconv.Itoa(int(barrierTimeout(instance).Seconds()))},
+		)
+	}
+	if instance.Spec.MaxLateness != nil {
+		initContainer.Env = append(initContainer.Env,
+			corev1.EnvVar{Name: "MAX_LATENESS_SECONDS", Value: strconv.Itoa(int(instance.Spec.MaxLateness.Seconds()))},
+		)
+	}
+
+	// the seccompProfile field is newer than the API this operator is built against, so the runtime default
+	// profile is asked for through the annotation the API server converts to it
+	podTemplate := &cronToPost.Spec.JobTemplate.Spec.Template
+	seccompAnnotation := corev1.SeccompContainerAnnotationKeyPrefix + warmupContainerInjectNameUID
+	if podTemplate.Annotations == nil {
+		podTemplate.Annotations = map[string]string{}
+	}
+	if _, exists := podTemplate.Annotations[seccompAnnotation]; !exists {
+		podTemplate.Annotations[seccompAnnotation] = corev1.SeccompProfileRuntimeDefault
+	}
+	addWarmUpPullSecrets(&podTemplate.Spec, r.WarmUpImagePullSecrets)
+
+	// the pod controller stamps each pod with its workload time, which reaches the warm-up container through this volume
+	podTemplate.Spec.Volumes = append(podTemplate.Spec.Volumes, workloadTimeVolumeSource(barrier))
+
+	// make the prescaledcronjob the controller of the autogenerated cron so it's cleaned up with the parent
+	if err := controllerutil.SetControllerReference(instance, cronToPost, r.Scheme); err != nil {
+		return nil, fmt.Errorf("Failed to set owner reference: %s", err)
 	}
-	cronToPost.ObjectMeta.OwnerReferences = append(cronToPost.ObjectMeta.OwnerReferences, ownerRef)
 
 	// add the init containers to the init containers array
 	cronToPost.Spec.JobTemplate.Spec.Template.Spec.InitContainers = append([]corev1.Container{initContainer}, cronToPost.Spec.JobTemplate.Spec.Template.Spec.InitContainers...)
 
 	// Add dynamic name to cron identify one to the other
-	autoGenName := "autogen-" + instance.ObjectMeta.Name
-	cronToPost.ObjectMeta.Name = autoGenName
+	cronToPost.ObjectMeta.Name = autogenName(instance)
 	cronToPost.ObjectMeta.Namespace = instance.ObjectMeta.Namespace
 
 	return cronToPost, nil
@@ -182,7 +364,7 @@ func (r *PreScaledCronJobReconciler) getCronJob(ctx context.Context, name string
 func (r *PreScaledCronJobReconciler) createCronJob(ctx context.Context, cronToPost *batchv1beta1.CronJob, objectHash string,
 	instance *pscv1alpha1.PreScaledCronJob, logger logr.Logger) (ctrl.Result, error) {
 
-	logger.Info(fmt.Sprintf("Creating cronjob: %v", cronToPost.ObjectMeta.Name))
+	logger.Info("Creating cronjob", "cronjob", cronToPost.ObjectMeta.Name)
 
 	// Add the object hash as an annotation so we can compare with future updates
 	if cronToPost.ObjectMeta.Annotations == nil {
@@ -190,12 +372,12 @@ func (r *PreScaledCronJobReconciler) createCronJob(ctx context.Context, cronToPo
 	}
 	cronToPost.ObjectMeta.Annotations[objectHashField] = objectHash
 	if err := r.Client.Create(ctx, cronToPost); err != nil {
-		r.Recorder.Event(instance, corev1.EventTypeWarning, "Create cronjob failed", fmt.Sprintf("Failed to create cronjob: %s", err))
+		recordEvent(ctx, r.Recorder, instance, corev1.EventTypeWarning, "Create cronjob failed", fmt.Sprintf("Failed to create cronjob: %s", err))
 		TrackCronAction(CronJobCreatedMetric, false)
 		return ctrl.Result{}, err
 	}
 
-	r.Recorder.Event(instance, corev1.EventTypeNormal, "Create cronjob successful", fmt.Sprintf("Created associated cronjob: %s", cronToPost.Name))
+	recordEvent(ctx, r.Recorder, instance, corev1.EventTypeNormal, "Create cronjob successful", fmt.Sprintf("Created associated cronjob: %s", cronToPost.Name))
 	TrackCronAction(CronJobCreatedMetric, true)
 	return ctrl.Result{}, nil
 }
@@ -203,7 +385,7 @@ func (r *PreScaledCronJobReconciler) createCronJob(ctx context.Context, cronToPo
 func (r *PreScaledCronJobReconciler) updateCronJob(ctx context.Context, existingCron *batchv1beta1.CronJob, cronToPost *batchv1beta1.CronJob,
 	objectHash string, instance *pscv1alpha1.PreScaledCronJob, logger logr.Logger) (ctrl.Result, error) {
 
-	logger.Info(fmt.Sprintf("Found associated cronjob: %v", existingCron.ObjectMeta.Name))
+	logger.V(debugLevel).Info("Found associated cronjob", "cronjob", existingCron.ObjectMeta.Name)
 
 	// does this belong to us? if not - leave it alone and error out
 	canUpdate := false
@@ -215,15 +397,15 @@ func (r *PreScaledCronJobReconciler) updateCronJob(ctx context.Context, existing
 	}
 
 	if !canUpdate {
-		r.Recorder.Event(instance, corev1.EventTypeWarning, "Cronjob already exists", fmt.Sprintf("A cronjob with this name already exists, and was not created by this operator : %s", existingCron.ObjectMeta.Name))
-		logger.Info(fmt.Sprintf("A cronjob with this name already exists, and was not created by this operator : %s", existingCron.ObjectMeta.Name))
+		recordEvent(ctx, r.Recorder, instance, corev1.EventTypeWarning, "Cronjob already exists", fmt.Sprintf("A cronjob with this name already exists, and was not created by this operator : %s", existingCron.ObjectMeta.Name))
+		logger.Info("A cronjob with this name already exists, and was not created by this operator", "cronjob", existingCron.ObjectMeta.Name)
 		return ctrl.Result{}, nil
 	}
 
 	// Is it the same as what we've just generated?
 	if existingCron.ObjectMeta.Annotations[objectHashField] == objectHash {
 		// it's the same - no-op
-		logger.Info("Autogenerated cronjob has not changed, will not recreate")
+		logger.V(debugLevel).Info("Autogenerated cronjob has not changed, will not recreate", "cronjob", existingCron.ObjectMeta.Name)
 		return ctrl.Result{}, nil
 	}
 
@@ -235,18 +417,175 @@ func (r *PreScaledCronJobReconciler) updateCronJob(ctx context.Context, existing
 
 	existingCron.ObjectMeta.Annotations[objectHashField] = objectHash
 	if err := r.Client.Update(ctx, existingCron); err != nil {
-		r.Recorder.Event(instance, corev1.EventTypeWarning, "Update of cronjob failed", fmt.Sprintf("Failed to update cronjob: %s", err))
+		recordEvent(ctx, r.Recorder, instance, corev1.EventTypeWarning, "Update of cronjob failed", fmt.Sprintf("Failed to update cronjob: %s", err))
 		logger.Error(err, "Failed to update cronjob")
 		TrackCronAction(CronJobUpdatedMetric, false)
 		return ctrl.Result{}, err
 	}
 
-	r.Recorder.Event(instance, corev1.EventTypeNormal, "Update of cronjob successful", fmt.Sprintf("Updated associated cronjob: %s", existingCron.Name))
-	logger.Info("Successfully updated cronjob")
+	recordEvent(ctx, r.Recorder, instance, corev1.EventTypeNormal, "Update of cronjob successful", fmt.Sprintf("Updated associated cronjob: %s", existingCron.Name))
+	logger.Info("Successfully updated cronjob", "cronjob", existingCron.ObjectMeta.Name, "hash", objectHash)
 	TrackCronAction(CronJobUpdatedMetric, true)
 	return ctrl.Result{}, nil
 }
 
+// updatePolicyCondition records any policy violations in status and raises an event when they're first seen
+func (r *PreScaledCronJobReconciler) updatePolicyCondition(ctx context.Context, instance *pscv1alpha1.PreScaledCronJob, violations []string) error {
+	changed := false
+	if len(violations) > 0 {
+		message := strings.Join(violations, "; ")
+		changed = setCondition(&instance.Status, pscv1alpha1.PolicyViolation, corev1.ConditionTrue, "PolicyViolated", message)
+		if changed {
+			recordEvent(ctx, r.Recorder, instance, corev1.EventTypeWarning, "Policy violation", fmt.Sprintf("Cronjob suspended: %s", message))
+		}
+	} else if findCondition(&instance.Status, pscv1alpha1.PolicyViolation) != nil {
+		changed = setCondition(&instance.Status, pscv1alpha1.PolicyViolation, corev1.ConditionFalse, "PolicySatisfied", "")
+	}
+
+	if !changed {
+		return nil
+	}
+	return r.Status().Update(ctx, instance)
+}
+
+// finalize removes the autogenerated cron and waits for its jobs and warm-up pods to go before releasing the finalizer
+func (r *PreScaledCronJobReconciler) finalize(ctx context.Context, instance *pscv1alpha1.PreScaledCronJob, logger logr.Logger) (ctrl.Result, error) {
+	if !containsString(instance.ObjectMeta.Finalizers, finalizerName) {
+		return ctrl.Result{}, nil
+	}
+
+	remaining, err := r.deleteChildren(ctx, instance)
+	if err != nil {
+		recordEvent(ctx, r.Recorder, instance, corev1.EventTypeWarning, "Deleting children", fmt.Sprintf("Failed to delete child resources: %s", err))
+		TrackCronAction(CronJobDeletedMetric, false)
+		return ctrl.Result{}, err
+	}
+
+	if remaining > 0 {
+		logger.Info("Waiting on child resources to be removed", "remaining", remaining)
+		return ctrl.Result{RequeueAfter: childCleanupInterval}, nil
+	}
+
+	instance.ObjectMeta.Finalizers = removeString(instance.ObjectMeta.Finalizers, finalizerName)
+	if err := r.Update(ctx, instance); err != nil {
+		recordEvent(ctx, r.Recorder, instance, corev1.EventTypeWarning, "Removing finalizer", fmt.Sprintf("Failed to remove finalizer: %s", err))
+		return ctrl.Result{}, err
+	}
+
+	TrackCronAction(CronJobDeletedMetric, true)
+	return ctrl.Result{}, nil
+}
+
+// deleteChildren issues deletes for the autogenerated cron and any of its jobs, returning how many children still exist
+func (r *PreScaledCronJobReconciler) deleteChildren(ctx context.Context, instance *pscv1alpha1.PreScaledCronJob) (int, error) {
+	remaining := 0
+	background := client.PropagationPolicy(metav1.DeletePropagationBackground)
+
+	crons, err := r.ownedCronJobs(ctx, instance)
+	if err != nil {
+		return 0, err
+	}
+	for i := range crons {
+		remaining++
+		if crons[i].ObjectMeta.DeletionTimestamp.IsZero() {
+			if err := r.Delete(ctx, &crons[i], background); err != nil && !errors.IsNotFound(err) {
+				return 0, err
+			}
+		}
+	}
+
+	// jobs are owned by the cron rather than us, so find them through the label injected into their pod template
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(instance.Namespace)); err != nil {
+		return 0, err
+	}
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if job.Spec.Template.ObjectMeta.Labels[PrimedCronLabel] != instance.Name {
+			continue
+		}
+		remaining++
+		if job.ObjectMeta.DeletionTimestamp.IsZero() {
+			if err := r.Delete(ctx, job, background); err != nil && !errors.IsNotFound(err) {
+				return 0, err
+			}
+		}
+	}
+
+	// warm-up pods are removed by the garbage collector once their job has gone
+	pods := &corev1.PodList{}
+	if err := r.podReader().List(ctx, pods, client.InNamespace(instance.Namespace), client.MatchingLabels{PrimedCronLabel: instance.Name}); err != nil {
+		return 0, err
+	}
+	remaining += len(pods.Items)
+
+	return remaining, nil
+}
+
+// ownedCronJobs lists the cronjobs the prescaledcronjob controls, one per schedule unless some are left over
+// from schedules which have since been removed
+func (r *PreScaledCronJobReconciler) ownedCronJobs(ctx context.Context, instance *pscv1alpha1.PreScaledCronJob) ([]batchv1beta1.CronJob, error) {
+	list := &batchv1beta1.CronJobList{}
+	if err := r.List(ctx, list, client.InNamespace(instance.Namespace)); err != nil {
+		return nil, err
+	}
+
+	owned := []batchv1beta1.CronJob{}
+	for _, cron := range list.Items {
+		if metav1.IsControlledBy(&cron, instance) {
+			owned = append(owned, cron)
+		}
+	}
+	return owned, nil
+}
+
+// deleteStaleCronJobs removes the cronjobs the prescaledcronjob controls which no schedule generates any more
+func (r *PreScaledCronJobReconciler) deleteStaleCronJobs(ctx context.Context, instance *pscv1alpha1.PreScaledCronJob, crons []primerCronJob) error {
+	owned, err := r.ownedCronJobs(ctx, instance)
+	if err != nil {
+		return err
+	}
+
+	for _, cron := range staleCronJobs(owned, crons) {
+		if err := r.Delete(ctx, cron, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			TrackCronAction(CronJobDeletedMetric, false)
+			return err
+		}
+		recordEvent(ctx, r.Recorder, instance, corev1.EventTypeNormal, "Delete cronjob successful", fmt.Sprintf("Deleted cronjob %s, its schedule was removed", cron.Name))
+		TrackCronAction(CronJobDeletedMetric, true)
+	}
+	return nil
+}
+
+// staleCronJobs picks the owned cronjobs which no schedule generates any more
+func staleCronJobs(owned []batchv1beta1.CronJob, crons []primerCronJob) []*batchv1beta1.CronJob {
+	stale := []*batchv1beta1.CronJob{}
+	for i := range owned {
+		if !owned[i].ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+		generated := false
+		for _, cron := range crons {
+			if cron.generated.Name == owned[i].Name {
+				generated = true
+			}
+		}
+		if !generated {
+			stale = append(stale, &owned[i])
+		}
+	}
+	return stale
+}
+
+// autogenName is the name of the cronjob generated for a prescaledcronjob, suffixed with the name of the schedule
+// for one returned by ScheduleInstances
+func autogenName(instance *pscv1alpha1.PreScaledCronJob) string {
+	if scheduleName := ScheduleName(instance); scheduleName != "" {
+		return autogenPrefix + instance.ObjectMeta.Name + "-" + scheduleName
+	}
+	return autogenPrefix + instance.ObjectMeta.Name
+}
+
 // Helper functions to check and remove string from a slice of strings.
 func containsString(slice []string, s string) bool {
 	for _, item := range slice {
@@ -257,9 +596,61 @@ func containsString(slice []string, s string) bool {
 	return false
 }
 
+func removeString(slice []string, s string) []string {
+	result := []string{}
+	for _, item := range slice {
+		if item != s {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// requestsForAllPreScaledCronJobs maps a change to a PreScalePolicy onto every prescaledcronjob it may affect
+func (r *PreScaledCronJobReconciler) requestsForAllPreScaledCronJobs(obj handler.MapObject) []ctrl.Request {
+	list := &pscv1alpha1.PreScaledCronJobList{}
+	if err := r.List(context.Background(), list); err != nil {
+		r.Log.Error(err, "Failed to list prescaledcronjobs for policy change")
+		return nil
+	}
+
+	requests := make([]ctrl.Request, 0, len(list.Items))
+	for _, item := range list.Items {
+		requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Name: item.Name, Namespace: item.Namespace}})
+	}
+	return requests
+}
+
+func (r *PreScaledCronJobReconciler) podReader() client.Reader {
+	if r.Pods != nil {
+		return r.Pods
+	}
+	return r.Client
+}
+
+func (r *PreScaledCronJobReconciler) policyReader() client.Reader {
+	if r.PolicyReader != nil {
+		return r.PolicyReader
+	}
+	return r.Client
+}
+
 // SetupWithManager sets up defaults
 func (r *PreScaledCronJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&pscv1alpha1.PreScaledCronJob{}).
-		Complete(r)
+		Owns(&batchv1beta1.CronJob{}).
+		WithEventFilter(ignoreStatusOnlyUpdates)
+
+	// cluster scoped policies and calendars can only be watched when the cache covers the whole cluster
+	if r.PolicyReader == nil {
+		builder = builder.Watches(&source.Kind{Type: &pscv1alpha1.PreScalePolicy{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.requestsForAllPreScaledCronJobs),
+		})
+		builder = builder.Watches(&source.Kind{Type: &pscv1alpha1.ExclusionCalendar{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.requestsForCalendarUsers),
+		})
+	}
+
+	return builder.Complete(r)
 }
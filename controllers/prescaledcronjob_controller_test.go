@@ -4,16 +4,19 @@ import (
 	"context"
 	"math/rand"
 	"strings"
+	"testing"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/require"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	//"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
 
 	batchv1 "k8s.io/api/batch/v1"
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
@@ -23,7 +26,6 @@ import (
 
 var namespace = "psc-system"
 var namePrefix = "psc-test-local-"
-var autogenPrefix = "autogen-"
 
 var _ = Describe("PrescaledCronJob Controller", func() {
 
@@ -76,6 +78,8 @@ var _ = Describe("PrescaledCronJob Controller", func() {
 			Expect(fetchedAutogenCron.Spec.Schedule).To(Equal("20 * * 10 *"))
 			Expect(len(fetchedAutogenCron.Spec.JobTemplate.Spec.Template.Spec.InitContainers)).To(Equal(1))
 			Expect(fetchedAutogenCron.OwnerReferences[0].UID).To(Equal(fetched.UID))
+			Expect(*fetchedAutogenCron.OwnerReferences[0].Controller).To(BeTrue())
+			Expect(fetched.Finalizers).To(ContainElement(finalizerName))
 		})
 	})
 
@@ -358,3 +362,77 @@ func randString() string {
 	}
 	return b.String()
 }
+
+func TestGenerateCronJob_SetsControllerOwnerReference(t *testing.T) {
+	instance := generatePSCSpec()
+	instance.UID = types.UID("psc-uid")
+	r := newTestReconciler(t)
+
+	cron, err := r.generateCronJob(&instance)
+	require.NoError(t, err)
+
+	require.Len(t, cron.OwnerReferences, 1)
+	ownerRef := cron.OwnerReferences[0]
+	require.Equal(t, pscv1alpha1.GroupVersion.String(), ownerRef.APIVersion)
+	require.Equal(t, "PreScaledCronJob", ownerRef.Kind)
+	require.Equal(t, instance.UID, ownerRef.UID)
+	require.True(t, *ownerRef.Controller)
+	require.True(t, *ownerRef.BlockOwnerDeletion)
+}
+
+func TestReconcile_ReplacesLegacyFinalizer(t *testing.T) {
+	instance := generatePSCSpec()
+	instance.Finalizers = []string{legacyFinalizerName}
+	r := newTestReconciler(t, &instance)
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	fetched := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(context.Background(), key, fetched))
+	require.Equal(t, []string{finalizerName}, fetched.Finalizers)
+}
+
+func TestReconcile_DeletionWaitsForChildren(t *testing.T) {
+	now := metav1.Now()
+	instance := generatePSCSpec()
+	instance.UID = types.UID("psc-uid")
+	instance.Finalizers = []string{finalizerName}
+	instance.DeletionTimestamp = &now
+
+	r := newTestReconciler(t)
+	cron, err := r.generateCronJob(&instance)
+	require.NoError(t, err)
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "warmup-pod",
+			Namespace: instance.Namespace,
+			Labels:    map[string]string{PrimedCronLabel: instance.Name},
+		},
+	}
+	r = newTestReconciler(t, &instance, cron, pod)
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	ctx := context.Background()
+
+	result, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+	require.Equal(t, childCleanupInterval, result.RequeueAfter)
+
+	// the cron is deleted straight away but the finalizer stays until the pod has gone
+	fetchedCron := &batchv1beta1.CronJob{}
+	require.True(t, errors.IsNotFound(r.Get(ctx, types.NamespacedName{Name: cron.Name, Namespace: cron.Namespace}, fetchedCron)))
+	fetched := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(ctx, key, fetched))
+	require.Contains(t, fetched.Finalizers, finalizerName)
+
+	require.NoError(t, r.Delete(ctx, pod))
+
+	result, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+	require.Equal(t, ctrl.Result{}, result)
+	released := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(ctx, key, released))
+	require.NotContains(t, released.Finalizers, finalizerName)
+}
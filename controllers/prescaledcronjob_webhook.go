@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/validate-psc-cronprimer-local-v1alpha1-prescaledcronjob,mutating=false,failurePolicy=fail,groups=psc.cronprimer.local,resources=prescaledcronjobs,verbs=create;update,versions=v1alpha1,name=vprescaledcronjob.cronprimer.local
+
+const validatePreScaledCronJobPath = "/validate-psc-cronprimer-local-v1alpha1-prescaledcronjob"
+
+// PreScaledCronJobValidator rejects prescaledcronjobs which break a PreScalePolicy before they are stored
+type PreScaledCronJobValidator struct {
+	Client  client.Reader
+	decoder *admission.Decoder
+}
+
+// Handle validates a create of a prescaledcronjob or an update which changes its spec. Other updates, such as
+// the operator removing its finalizer or writing status, are let through so existing objects which break a
+// policy added since can still be cleaned up.
+func (v *PreScaledCronJobValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	instance := &pscv1alpha1.PreScaledCronJob{}
+	if err := v.decoder.Decode(req, instance); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if instance.DeletionTimestamp != nil {
+		return admission.Allowed("prescaledcronjob is being deleted")
+	}
+	if req.Operation == admissionv1beta1.Update {
+		old := &pscv1alpha1.PreScaledCronJob{}
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if equality.Semantic.DeepEqual(old.Spec, instance.Spec) {
+			return admission.Allowed("spec is unchanged")
+		}
+	}
+	if instance.Namespace == "" {
+		instance.Namespace = req.Namespace
+	}
+
+	violations, err := policyViolations(ctx, v.Client, instance)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(violations) > 0 {
+		return admission.Denied(strings.Join(violations, "; "))
+	}
+
+	return admission.Allowed("")
+}
+
+// InjectDecoder is called by the webhook server to provide a decoder
+func (v *PreScaledCronJobValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// SetupWebhookWithManager registers the validating webhook with the manager's webhook server
+func (v *PreScaledCronJobValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(validatePreScaledCronJobPath, &webhook.Admission{Handler: v})
+	return nil
+}
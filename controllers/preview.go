@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// PreviewPreScaledCronJobsPath lists every prescaledcronjob with its next primer and workload times
+	PreviewPreScaledCronJobsPath = "/api/v1alpha1/prescaledcronjobs"
+	// PreviewTimelinePath returns the upcoming warm-ups grouped by nodepool
+	PreviewTimelinePath = "/api/v1alpha1/timeline"
+
+	defaultTimelineHours = 24
+	maxTimelineHours     = 24 * 7
+)
+
+// PreviewHandler serves a read-only view of when prescaledcronjobs will next warm up the cluster
+type PreviewHandler struct {
+	Client client.Reader
+	Log    logr.Logger
+
+	now func() time.Time
+}
+
+// PreScaledCronJobPreview is the next primed run of a single prescaledcronjob, or of one of its schedules
+type PreScaledCronJobPreview struct {
+	Name           string     `json:"name"`
+	Namespace      string     `json:"namespace"`
+	Nodepool       string     `json:"nodepool"`
+	ScheduleName   string     `json:"scheduleName,omitempty"`
+	Schedule       string     `json:"schedule"`
+	PrimerSchedule string     `json:"primerSchedule,omitempty"`
+	NextPrimer     *time.Time `json:"nextPrimer,omitempty"`
+	NextWorkload   *time.Time `json:"nextWorkload,omitempty"`
+	Error          string     `json:"error,omitempty"`
+}
+
+// TimelineEntry is a single upcoming warm-up on a nodepool
+type TimelineEntry struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	PrimedRun
+}
+
+// NodepoolTimeline is every upcoming warm-up for a nodepool in time order
+type NodepoolTimeline struct {
+	Nodepool string          `json:"nodepool"`
+	WarmUps  []TimelineEntry `json:"warmUps"`
+}
+
+// Register adds the preview endpoints to a mux
+func (h *PreviewHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc(PreviewPreScaledCronJobsPath, h.servePreScaledCronJobs)
+	mux.HandleFunc(PreviewTimelinePath, h.serveTimeline)
+}
+
+func (h *PreviewHandler) servePreScaledCronJobs(w http.ResponseWriter, r *http.Request) {
+	instances, ok := h.listInstances(w, r)
+	if !ok {
+		return
+	}
+
+	now := h.currentTime()
+	previews := []PreScaledCronJobPreview{}
+	for i := range instances {
+		for _, instance := range ScheduleInstances(&instances[i]) {
+			previews = append(previews, previewSchedule(instance, now))
+		}
+	}
+
+	h.writeJSON(w, previews)
+}
+
+// previewSchedule finds the next primer and workload times of a single schedule
+func previewSchedule(instance *pscv1alpha1.PreScaledCronJob, now time.Time) PreScaledCronJobPreview {
+	preview := PreScaledCronJobPreview{
+		Name:         instance.Name,
+		Namespace:    instance.Namespace,
+		Nodepool:     nodepoolFor(&instance.Spec.CronJob.Spec.JobTemplate.Spec.Template.Spec),
+		ScheduleName: ScheduleName(instance),
+		Schedule:     instance.Spec.CronJob.Spec.Schedule,
+	}
+
+	primerSchedule, err := PrimerScheduleFor(instance)
+	if err != nil {
+		preview.Error = err.Error()
+		return preview
+	}
+	preview.PrimerSchedule = primerSchedule
+
+	primerTimes, err := NextFireTimes(primerSchedule, now, 1)
+	if err == nil && len(primerTimes) > 0 {
+		preview.NextPrimer = &primerTimes[0]
+	}
+	workloadTimes, err := NextFireTimes(preview.Schedule, now, 1)
+	if err == nil && len(workloadTimes) > 0 {
+		preview.NextWorkload = &workloadTimes[0]
+	}
+
+	return preview
+}
+
+func (h *PreviewHandler) serveTimeline(w http.ResponseWriter, r *http.Request) {
+	hours := defaultTimelineHours
+	if value := r.URL.Query().Get("hours"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxTimelineHours {
+			http.Error(w, "hours must be a whole number between 1 and 168", http.StatusBadRequest)
+			return
+		}
+		hours = parsed
+	}
+
+	instances, ok := h.listInstances(w, r)
+	if !ok {
+		return
+	}
+
+	now := h.currentTime()
+	timeline, err := BuildTimeline(instances, now, now.Add(time.Duration(hours)*time.Hour))
+	if err != nil {
+		h.Log.Error(err, "Failed to build timeline")
+	}
+
+	h.writeJSON(w, timeline)
+}
+
+// BuildTimeline groups the primed runs of prescaledcronjobs by nodepool. Objects whose schedules can't be
+// parsed are left out and reported through the returned error.
+func BuildTimeline(instances []pscv1alpha1.PreScaledCronJob, from time.Time, until time.Time) ([]NodepoolTimeline, error) {
+	var lastErr error
+	byNodepool := map[string][]TimelineEntry{}
+	for i := range instances {
+		instance := &instances[i]
+		runs, err := UpcomingRuns(instance, from, until)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		nodepool := nodepoolFor(&instance.Spec.CronJob.Spec.JobTemplate.Spec.Template.Spec)
+		for _, run := range runs {
+			byNodepool[nodepool] = append(byNodepool[nodepool], TimelineEntry{Name: instance.Name, Namespace: instance.Namespace, PrimedRun: run})
+		}
+	}
+
+	timeline := []NodepoolTimeline{}
+	for nodepool, warmUps := range byNodepool {
+		sort.SliceStable(warmUps, func(i, j int) bool {
+			return warmUps[i].PrimerAt.Before(warmUps[j].PrimerAt)
+		})
+		timeline = append(timeline, NodepoolTimeline{Nodepool: nodepool, WarmUps: warmUps})
+	}
+	sort.Slice(timeline, func(i, j int) bool {
+		return timeline[i].Nodepool < timeline[j].Nodepool
+	})
+
+	return timeline, lastErr
+}
+
+func (h *PreviewHandler) listInstances(w http.ResponseWriter, r *http.Request) ([]pscv1alpha1.PreScaledCronJob, bool) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	list := &pscv1alpha1.PreScaledCronJobList{}
+	if err := h.Client.List(context.Background(), list); err != nil {
+		h.Log.Error(err, "Failed to list prescaledcronjobs")
+		http.Error(w, "failed to list prescaledcronjobs", http.StatusServiceUnavailable)
+		return nil, false
+	}
+
+	return list.Items, true
+}
+
+func (h *PreviewHandler) writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.Log.Error(err, "Failed to write preview response")
+	}
+}
+
+func (h *PreviewHandler) currentTime() time.Time {
+	if h.now != nil {
+		return h.now()
+	}
+	return time.Now()
+}
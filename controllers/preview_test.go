@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func newTestPreviewServer(t *testing.T, instances ...pscv1alpha1.PreScaledCronJob) *httptest.Server {
+	objs := []runtime.Object{}
+	for i := range instances {
+		objs = append(objs, &instances[i])
+	}
+
+	handler := &PreviewHandler{
+		Client: newTestClient(t, objs...),
+		Log:    ctrl.Log.WithName("test"),
+		now: func() time.Time {
+			return time.Date(2020, 1, 29, 12, 3, 5, 0, time.UTC)
+		},
+	}
+	mux := http.NewServeMux()
+	handler.Register(mux)
+
+	return httptest.NewServer(mux)
+}
+
+func newPreviewPSC(name string, schedule string, warmupMinutes int, nodepool string) pscv1alpha1.PreScaledCronJob {
+	instance := generatePSCSpec()
+	instance.Name = name
+	instance.Spec.WarmUpTimeMins = warmupMinutes
+	instance.Spec.CronJob.Spec.Schedule = schedule
+	if nodepool != "" {
+		instance.Spec.CronJob.Spec.JobTemplate.Spec.Template.Spec.NodeSelector = map[string]string{defaultNodepoolLabel: nodepool}
+	}
+	return instance
+}
+
+func TestPreview_ListsNextPrimerAndWorkloadTimes(t *testing.T) {
+	server := newTestPreviewServer(t,
+		newPreviewPSC("hourly", "30 * * * *", 10, "gpu"),
+		newPreviewPSC("broken", "* * * * *", 10, ""),
+	)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + PreviewPreScaledCronJobsPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	previews := []PreScaledCronJobPreview{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&previews))
+	require.Len(t, previews, 2)
+
+	byName := map[string]PreScaledCronJobPreview{}
+	for _, preview := range previews {
+		byName[preview.Name] = preview
+	}
+
+	require.Equal(t, "gpu", byName["hourly"].Nodepool)
+	require.Equal(t, "20 * * * *", byName["hourly"].PrimerSchedule)
+	require.Equal(t, time.Date(2020, 1, 29, 12, 20, 0, 0, time.UTC), byName["hourly"].NextPrimer.UTC())
+	require.Equal(t, time.Date(2020, 1, 29, 12, 30, 0, 0, time.UTC), byName["hourly"].NextWorkload.UTC())
+
+	require.Equal(t, noNodepool, byName["broken"].Nodepool)
+	require.NotEmpty(t, byName["broken"].Error)
+	require.Nil(t, byName["broken"].NextPrimer)
+}
+
+func TestPreview_TimelineGroupsByNodepool(t *testing.T) {
+	server := newTestPreviewServer(t,
+		newPreviewPSC("nightly", "0 0 * * *", 15, "gpu"),
+		newPreviewPSC("hourly", "30 * * * *", 10, "gpu"),
+		newPreviewPSC("other", "0 0 * * *", 5, ""),
+	)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + PreviewTimelinePath + "?hours=2")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	timeline := []NodepoolTimeline{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&timeline))
+
+	// only hourly warms up within the next two hours
+	require.Len(t, timeline, 1)
+	require.Equal(t, "gpu", timeline[0].Nodepool)
+	require.Len(t, timeline[0].WarmUps, 2)
+	require.Equal(t, "hourly", timeline[0].WarmUps[0].Name)
+	require.Equal(t, time.Date(2020, 1, 29, 12, 20, 0, 0, time.UTC), timeline[0].WarmUps[0].PrimerAt.UTC())
+	require.Equal(t, time.Date(2020, 1, 29, 12, 30, 0, 0, time.UTC), timeline[0].WarmUps[0].WorkloadAt.UTC())
+}
+
+func TestPreview_TimelineRejectsInvalidHours(t *testing.T) {
+	server := newTestPreviewServer(t)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + PreviewTimelinePath + "?hours=bananas")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
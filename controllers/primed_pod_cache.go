@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var _ client.Reader = &PrimedPodCache{}
+
+// PrimedPodCache holds only the pods carrying the primedcron label, with one informer per watched namespace,
+// so the operator doesn't need to cache every pod in the cluster. It reads like a client for pods and is
+// started by the manager.
+type PrimedPodCache struct {
+	factories map[string]informers.SharedInformerFactory
+	informers map[string]toolscache.SharedIndexInformer
+	listers   map[string]listersv1.PodLister
+}
+
+// NewPrimedPodCache creates a cache of primed pods in the given namespaces, or in every namespace when none
+// are given
+func NewPrimedPodCache(clientset kubernetes.Interface, namespaces []string, resync time.Duration) *PrimedPodCache {
+	if len(namespaces) == 0 {
+		namespaces = []string{corev1.NamespaceAll}
+	}
+
+	c := &PrimedPodCache{
+		factories: map[string]informers.SharedInformerFactory{},
+		informers: map[string]toolscache.SharedIndexInformer{},
+		listers:   map[string]listersv1.PodLister{},
+	}
+	for _, namespace := range namespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(clientset, resync,
+			informers.WithNamespace(namespace),
+			informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				// a bare key selects every pod which has the label, whatever its value
+				options.LabelSelector = PrimedCronLabel
+			}))
+		pods := factory.Core().V1().Pods()
+
+		c.factories[namespace] = factory
+		c.informers[namespace] = pods.Informer()
+		c.listers[namespace] = pods.Lister()
+	}
+	return c
+}
+
+// Start runs the informers until stop is closed
+func (c *PrimedPodCache) Start(stop <-chan struct{}) error {
+	for _, factory := range c.factories {
+		factory.Start(stop)
+	}
+	for namespace, factory := range c.factories {
+		for _, synced := range factory.WaitForCacheSync(stop) {
+			if !synced {
+				return fmt.Errorf("primed pod cache for namespace %q failed to sync", namespace)
+			}
+		}
+	}
+
+	<-stop
+	return nil
+}
+
+// Sources returns a watch source for each namespace the cache covers
+func (c *PrimedPodCache) Sources() []source.Source {
+	sources := []source.Source{}
+	for _, namespace := range c.namespaces() {
+		sources = append(sources, &source.Informer{Informer: c.informers[namespace]})
+	}
+	return sources
+}
+
+// Get retrieves a primed pod, returning a not found error for pods without the primedcron label
+func (c *PrimedPodCache) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return fmt.Errorf("primed pod cache only holds pods, not %T", obj)
+	}
+
+	lister, err := c.listerFor(key.Namespace)
+	if err != nil {
+		return err
+	}
+
+	cached, err := lister.Pods(key.Namespace).Get(key.Name)
+	if err != nil {
+		return err
+	}
+	cached.DeepCopyInto(pod)
+	return nil
+}
+
+// List retrieves the primed pods matching the namespace and label options
+func (c *PrimedPodCache) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	podList, ok := list.(*corev1.PodList)
+	if !ok {
+		return fmt.Errorf("primed pod cache only holds pods, not %T", list)
+	}
+
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	selector := listOpts.LabelSelector
+	if selector == nil {
+		selector = labels.Everything()
+	}
+
+	listers := map[string]listersv1.PodLister{}
+	if listOpts.Namespace == corev1.NamespaceAll {
+		listers = c.listers
+	} else {
+		lister, err := c.listerFor(listOpts.Namespace)
+		if err != nil {
+			return err
+		}
+		listers[listOpts.Namespace] = lister
+	}
+
+	podList.Items = []corev1.Pod{}
+	for _, lister := range listers {
+		var pods []*corev1.Pod
+		var err error
+		if listOpts.Namespace == corev1.NamespaceAll {
+			pods, err = lister.List(selector)
+		} else {
+			pods, err = lister.Pods(listOpts.Namespace).List(selector)
+		}
+		if err != nil {
+			return err
+		}
+		for _, pod := range pods {
+			podList.Items = append(podList.Items, *pod.DeepCopy())
+		}
+	}
+	return nil
+}
+
+// listerFor finds the lister covering a namespace, preferring the cluster wide one when it exists
+func (c *PrimedPodCache) listerFor(namespace string) (listersv1.PodLister, error) {
+	if lister, exists := c.listers[corev1.NamespaceAll]; exists {
+		return lister, nil
+	}
+	if lister, exists := c.listers[namespace]; exists {
+		return lister, nil
+	}
+	return nil, fmt.Errorf("namespace %q is not watched by the primed pod cache", namespace)
+}
+
+func (c *PrimedPodCache) namespaces() []string {
+	namespaces := []string{}
+	for namespace := range c.informers {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
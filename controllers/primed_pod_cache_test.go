@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newCachedPod(namespace string, name string, prescaledName string) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	if prescaledName != "" {
+		pod.Labels = map[string]string{PrimedCronLabel: prescaledName}
+	}
+	return pod
+}
+
+func startPrimedPodCache(t *testing.T, namespaces []string) *PrimedPodCache {
+	clientset := fake.NewSimpleClientset(
+		newCachedPod("team-a", "primed", "nightly"),
+		newCachedPod("team-a", "other-primed", "hourly"),
+		newCachedPod("team-a", "unrelated", ""),
+		newCachedPod("team-b", "primed", "nightly"),
+	)
+	podCache := NewPrimedPodCache(clientset, namespaces, 0)
+
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	for _, factory := range podCache.factories {
+		factory.Start(stop)
+		factory.WaitForCacheSync(stop)
+	}
+	return podCache
+}
+
+func TestPrimedPodCache_OnlyHoldsLabelledPodsInWatchedNamespaces(t *testing.T) {
+	podCache := startPrimedPodCache(t, []string{"team-a"})
+	ctx := context.Background()
+
+	pod := &corev1.Pod{}
+	require.NoError(t, podCache.Get(ctx, types.NamespacedName{Name: "primed", Namespace: "team-a"}, pod))
+	require.Equal(t, "nightly", pod.Labels[PrimedCronLabel])
+
+	err := podCache.Get(ctx, types.NamespacedName{Name: "unrelated", Namespace: "team-a"}, &corev1.Pod{})
+	require.True(t, errors.IsNotFound(err))
+
+	err = podCache.Get(ctx, types.NamespacedName{Name: "primed", Namespace: "team-b"}, &corev1.Pod{})
+	require.Error(t, err)
+
+	pods := &corev1.PodList{}
+	require.NoError(t, podCache.List(ctx, pods, client.InNamespace("team-a"), client.MatchingLabels{PrimedCronLabel: "nightly"}))
+	require.Len(t, pods.Items, 1)
+	require.Equal(t, "primed", pods.Items[0].Name)
+
+	require.Len(t, podCache.Sources(), 1)
+}
+
+func TestPrimedPodCache_WatchesEveryNamespaceByDefault(t *testing.T) {
+	podCache := startPrimedPodCache(t, nil)
+
+	pods := &corev1.PodList{}
+	require.NoError(t, podCache.List(context.Background(), pods))
+	require.Len(t, pods.Items, 3)
+
+	pods = &corev1.PodList{}
+	require.NoError(t, podCache.List(context.Background(), pods, client.InNamespace("team-b")))
+	require.Len(t, pods.Items, 1)
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	retryBaseDelay = time.Second * 5
+	retryMaxDelay  = time.Minute * 5
+
+	// maxPermanentRetries bounds how often an object whose spec can't be reconciled is retried, it is tried
+	// again whenever it changes
+	maxPermanentRetries = 5
+
+	permanentErrorClass = "permanent"
+	transientErrorClass = "transient"
+)
+
+// reconcileError marks an error which will keep failing until the prescaledcronjob is changed
+type reconcileError struct {
+	err error
+}
+
+func (e *reconcileError) Error() string {
+	return e.err.Error()
+}
+
+// permanentError wraps an error caused by the prescaledcronjob's spec rather than the cluster
+func permanentError(err error) error {
+	return &reconcileError{err: err}
+}
+
+// errorClass decides whether an error is worth retrying, anything not known to be permanent is assumed to be
+// caused by something outside the object, such as the API server or a policy lookup
+func errorClass(err error) string {
+	if _, ok := err.(*reconcileError); ok {
+		return permanentErrorClass
+	}
+	if errors.IsInvalid(err) || errors.IsBadRequest(err) {
+		return permanentErrorClass
+	}
+	return transientErrorClass
+}
+
+// retryBackoff is how long to wait before the given retry, doubling each time up to retryMaxDelay
+func retryBackoff(retries int32) time.Duration {
+	delay := retryBaseDelay
+	for i := int32(1); i < retries && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		return retryMaxDelay
+	}
+	return delay
+}
+
+// handleReconcileError records the outcome of a reconcile in status and decides when to try again. Transient
+// errors are retried with backoff indefinitely, permanent ones maxPermanentRetries times for each generation.
+func (r *PreScaledCronJobReconciler) handleReconcileError(ctx context.Context, instance *pscv1alpha1.PreScaledCronJob,
+	result ctrl.Result, reconcileErr error, logger logr.Logger) (ctrl.Result, error) {
+
+	if reconcileErr == nil {
+		if instance.Status.Retries == 0 && instance.Status.LastError == "" {
+			return result, nil
+		}
+		instance.Status.Retries = 0
+		instance.Status.RetriesGeneration = 0
+		instance.Status.LastError = ""
+		instance.Status.LastErrorTime = nil
+		if err := r.Status().Update(ctx, instance); err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "Failed to clear the last error from status")
+			return ctrl.Result{}, err
+		}
+		return result, nil
+	}
+
+	class := errorClass(reconcileErr)
+	now := metav1.Now()
+	// a changed object gets a fresh set of retries, even when it fails the same way
+	if instance.Status.RetriesGeneration != instance.Generation {
+		instance.Status.Retries = 0
+		instance.Status.RetriesGeneration = instance.Generation
+	}
+	instance.Status.Retries++
+	instance.Status.LastError = reconcileErr.Error()
+	instance.Status.LastErrorTime = &now
+	TrackReconcileError(instance.Name, class)
+
+	if err := r.Status().Update(ctx, instance); err != nil && !errors.IsNotFound(err) {
+		// the status can't be written either, fall back to the controller's own backoff
+		logger.Error(err, "Failed to record the last error in status")
+		return ctrl.Result{}, reconcileErr
+	}
+
+	if class == permanentErrorClass && instance.Status.Retries >= maxPermanentRetries {
+		if instance.Status.Retries == maxPermanentRetries {
+			recordEvent(ctx, r.Recorder, instance, corev1.EventTypeWarning, "RetriesExhausted",
+				fmt.Sprintf("Giving up after %d attempts, the prescaledcronjob needs to be changed: %s", instance.Status.Retries, reconcileErr))
+		}
+		return ctrl.Result{}, nil
+	}
+
+	delay := retryBackoff(instance.Status.Retries)
+	logger.Info("Retrying after error", "class", class, "delay", delay.String(), "retries", instance.Status.Retries, "error", reconcileErr.Error())
+	return ctrl.Result{RequeueAfter: delay}, nil
+}
+
+// ignoreStatusOnlyUpdates drops the events caused by the operator writing a prescaledcronjob's status, which
+// would otherwise requeue it straight away and defeat the retry backoff
+var ignoreStatusOnlyUpdates = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldInstance, ok := e.ObjectOld.(*pscv1alpha1.PreScaledCronJob)
+		if !ok {
+			return true
+		}
+		newInstance, ok := e.ObjectNew.(*pscv1alpha1.PreScaledCronJob)
+		if !ok {
+			return true
+		}
+
+		withNewStatus := oldInstance.DeepCopy()
+		withNewStatus.Status = newInstance.Status
+		withNewStatus.ResourceVersion = newInstance.ResourceVersion
+		withNewStatus.ManagedFields = newInstance.ManagedFields
+		return !equality.Semantic.DeepEqual(withNewStatus, newInstance)
+	},
+}
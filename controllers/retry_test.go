@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	require.Equal(t, time.Second*5, retryBackoff(1))
+	require.Equal(t, time.Second*10, retryBackoff(2))
+	require.Equal(t, time.Second*40, retryBackoff(4))
+	require.Equal(t, time.Minute*5, retryBackoff(100))
+}
+
+func TestErrorClass(t *testing.T) {
+	cronJobs := schema.GroupResource{Group: "batch", Resource: "cronjobs"}
+
+	require.Equal(t, permanentErrorClass, errorClass(permanentError(fmt.Errorf("bad schedule"))))
+	require.Equal(t, permanentErrorClass, errorClass(errors.NewInvalid(schema.GroupKind{Group: "batch", Kind: "CronJob"}, "autogen-x", field.ErrorList{})))
+	require.Equal(t, transientErrorClass, errorClass(errors.NewConflict(cronJobs, "autogen-x", fmt.Errorf("changed"))))
+	require.Equal(t, transientErrorClass, errorClass(errors.NewServiceUnavailable("down")))
+	require.Equal(t, transientErrorClass, errorClass(fmt.Errorf("policy lookup failed")))
+}
+
+func TestReconcile_InvalidSpecRetriesThenGivesUp(t *testing.T) {
+	instance := generatePSCSpec()
+	instance.Finalizers = []string{finalizerName}
+	instance.Spec.CronJob.Spec.Schedule = "not a schedule"
+	r := newTestReconciler(t, &instance)
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	ctx := context.Background()
+
+	for attempt := int32(1); attempt < maxPermanentRetries; attempt++ {
+		result, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+		require.NoError(t, err)
+		require.Equal(t, retryBackoff(attempt), result.RequeueAfter)
+	}
+
+	result, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+	require.Equal(t, ctrl.Result{}, result)
+
+	fetched := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(ctx, key, fetched))
+	require.Equal(t, int32(maxPermanentRetries), fetched.Status.Retries)
+	require.Contains(t, fetched.Status.LastError, "not a schedule")
+	require.NotNil(t, fetched.Status.LastErrorTime)
+
+	// a change which still doesn't fix the spec is retried afresh
+	fetched.Spec.CronJob.Spec.Schedule = "still not a schedule"
+	fetched.Generation++
+	require.NoError(t, r.Update(ctx, fetched))
+	result, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+	require.Equal(t, retryBackoff(1), result.RequeueAfter)
+	require.NoError(t, r.Get(ctx, key, fetched))
+	require.Equal(t, int32(1), fetched.Status.Retries)
+	require.Equal(t, fetched.Generation, fetched.Status.RetriesGeneration)
+
+	// fixing the spec clears the error
+	fetched.Spec.CronJob.Spec.Schedule = "30 * * * *"
+	fetched.Generation++
+	require.NoError(t, r.Update(ctx, fetched))
+	_, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	fixed := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(ctx, key, fixed))
+	require.Zero(t, fixed.Status.Retries)
+	require.Empty(t, fixed.Status.LastError)
+	require.Nil(t, fixed.Status.LastErrorTime)
+}
+
+func TestIgnoreStatusOnlyUpdates(t *testing.T) {
+	old := generatePSCSpec()
+	old.ResourceVersion = "1"
+
+	statusOnly := old.DeepCopy()
+	statusOnly.ResourceVersion = "2"
+	statusOnly.Status.Retries = 1
+
+	specChange := old.DeepCopy()
+	specChange.ResourceVersion = "2"
+	specChange.Spec.WarmUpTimeMins = 20
+
+	require.False(t, ignoreStatusOnlyUpdates.Update(event.UpdateEvent{ObjectOld: &old, ObjectNew: statusOnly}))
+	require.True(t, ignoreStatusOnlyUpdates.Update(event.UpdateEvent{ObjectOld: &old, ObjectNew: specChange}))
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"fmt"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ScheduleLabel is added to the primed pods of a prescaledcronjob with several schedules and holds the name of
+// the schedule they warm up for
+const ScheduleLabel = "psc.cronprimer.local/schedule"
+
+// ScheduleInstances returns the prescaledcronjob as seen by each of its schedules: a copy with the schedule,
+// primer schedule and warm-up of the entry in place of the top level ones, so everything written for a single
+// schedule works on it unchanged. A prescaledcronjob without schedules is returned as it is.
+func ScheduleInstances(instance *pscv1alpha1.PreScaledCronJob) []*pscv1alpha1.PreScaledCronJob {
+	if len(instance.Spec.Schedules) == 0 {
+		return []*pscv1alpha1.PreScaledCronJob{instance}
+	}
+
+	views := make([]*pscv1alpha1.PreScaledCronJob, 0, len(instance.Spec.Schedules))
+	for _, entry := range instance.Spec.Schedules {
+		views = append(views, forScheduleEntry(instance, entry))
+	}
+	return views
+}
+
+// forScheduleEntry copies the prescaledcronjob with the entry applied, the copy keeps the entry as its only
+// schedule so applying it again changes nothing
+func forScheduleEntry(instance *pscv1alpha1.PreScaledCronJob, entry pscv1alpha1.ScheduleEntry) *pscv1alpha1.PreScaledCronJob {
+	view := instance.DeepCopy()
+	view.Spec.Schedules = []pscv1alpha1.ScheduleEntry{*entry.DeepCopy()}
+	view.Spec.CronJob.Spec.Schedule = entry.Schedule
+	view.Spec.PrimerSchedule = entry.PrimerSchedule
+	if entry.WarmUpDuration != nil {
+		view.Spec.WarmUpDuration = entry.WarmUpDuration.DeepCopy()
+	}
+	return view
+}
+
+// singleSchedule returns the prescaledcronjob with its schedule entry applied when it has exactly one, failing
+// when it has several as they can't be described by one schedule
+func singleSchedule(instance *pscv1alpha1.PreScaledCronJob) (*pscv1alpha1.PreScaledCronJob, error) {
+	switch len(instance.Spec.Schedules) {
+	case 0:
+		return instance, nil
+	case 1:
+		return forScheduleEntry(instance, instance.Spec.Schedules[0]), nil
+	default:
+		return nil, fmt.Errorf("prescaledcronjob %s has %d schedules, each has to be handled on its own", instance.Name, len(instance.Spec.Schedules))
+	}
+}
+
+// ScheduleName is the name of the schedule entry a prescaledcronjob returned by ScheduleInstances is for, empty
+// when it has no schedules
+func ScheduleName(instance *pscv1alpha1.PreScaledCronJob) string {
+	if len(instance.Spec.Schedules) != 1 {
+		return ""
+	}
+	return instance.Spec.Schedules[0].Name
+}
+
+// validateSchedules checks the names of the schedule entries can tell their cronjobs and pods apart
+func validateSchedules(instance *pscv1alpha1.PreScaledCronJob) error {
+	seen := map[string]bool{}
+	for _, entry := range instance.Spec.Schedules {
+		if entry.Name == "" {
+			return fmt.Errorf("schedule %q has no name", entry.Schedule)
+		}
+		if seen[entry.Name] {
+			return fmt.Errorf("schedule name %s is used more than once", entry.Name)
+		}
+		seen[entry.Name] = true
+	}
+	return nil
+}
+
+// ScheduleInstanceForPod returns the prescaledcronjob as seen by the schedule a primed pod warms up for
+func ScheduleInstanceForPod(instance *pscv1alpha1.PreScaledCronJob, pod *corev1.Pod) (*pscv1alpha1.PreScaledCronJob, error) {
+	if len(instance.Spec.Schedules) == 0 {
+		return instance, nil
+	}
+
+	name, labelled := pod.Labels[ScheduleLabel]
+	if !labelled {
+		return nil, fmt.Errorf("pod %s has no %s label", pod.Name, ScheduleLabel)
+	}
+	for _, entry := range instance.Spec.Schedules {
+		if entry.Name == name {
+			return forScheduleEntry(instance, entry), nil
+		}
+	}
+	return nil, fmt.Errorf("pod %s warms up for schedule %s which no longer exists", pod.Name, name)
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newMultiSchedulePSC(name string) pscv1alpha1.PreScaledCronJob {
+	instance := newPreviewPSC(name, "0 0 1 1 *", 10, "")
+	instance.Spec.Schedules = []pscv1alpha1.ScheduleEntry{
+		{Name: "weekday", Schedule: "0 8 * * 1-5", WarmUpDuration: &metav1.Duration{Duration: time.Minute * 15}},
+		{Name: "weekend", Schedule: "0 10 * * 0,6"},
+	}
+	return instance
+}
+
+func TestScheduleInstances(t *testing.T) {
+	instance := newMultiSchedulePSC("daily")
+	instance.Spec.PrimerSchedule = "0 0 * * *"
+
+	views := ScheduleInstances(&instance)
+	require.Len(t, views, 2)
+
+	require.Equal(t, "weekday", ScheduleName(views[0]))
+	require.Equal(t, "autogen-daily-weekday", autogenName(views[0]))
+	require.Equal(t, time.Minute*15, WarmUpFor(views[0]))
+	primerSchedule, err := PrimerScheduleFor(views[0])
+	require.NoError(t, err)
+	require.Equal(t, "45 7 * * 1-5", primerSchedule)
+
+	// the top level warm-up is the default, the top level primer schedule belongs to the ignored cronjob schedule
+	require.Equal(t, time.Minute*10, WarmUpFor(views[1]))
+	primerSchedule, err = PrimerScheduleFor(views[1])
+	require.NoError(t, err)
+	require.Equal(t, "50 9 * * 0,6", primerSchedule)
+
+	// the views are their own single schedule, the object as a whole isn't
+	require.Equal(t, []*pscv1alpha1.PreScaledCronJob{views[1]}, ScheduleInstances(views[1]))
+	_, err = PrimerScheduleFor(&instance)
+	require.Error(t, err)
+
+	single := newPreviewPSC("hourly", "30 * * * *", 10, "")
+	require.Equal(t, []*pscv1alpha1.PreScaledCronJob{&single}, ScheduleInstances(&single))
+	require.Equal(t, "autogen-hourly", autogenName(&single))
+}
+
+func TestValidateSchedules(t *testing.T) {
+	instance := newMultiSchedulePSC("daily")
+	require.NoError(t, validateSchedules(&instance))
+
+	instance.Spec.Schedules[1].Name = "weekday"
+	require.Error(t, validateSchedules(&instance))
+}
+
+func TestUpcomingRuns_MergesSchedules(t *testing.T) {
+	instance := newMultiSchedulePSC("daily")
+	// a Friday
+	from := time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	runs, err := UpcomingRuns(&instance, from, from.Add(time.Hour*24*3))
+	require.NoError(t, err)
+	require.Equal(t, []PrimedRun{
+		{PrimerAt: time.Date(2020, 1, 31, 7, 45, 0, 0, time.UTC), WorkloadAt: time.Date(2020, 1, 31, 8, 0, 0, 0, time.UTC), Schedule: "weekday"},
+		{PrimerAt: time.Date(2020, 2, 1, 9, 50, 0, 0, time.UTC), WorkloadAt: time.Date(2020, 2, 1, 10, 0, 0, 0, time.UTC), Schedule: "weekend"},
+		{PrimerAt: time.Date(2020, 2, 2, 9, 50, 0, 0, time.UTC), WorkloadAt: time.Date(2020, 2, 2, 10, 0, 0, 0, time.UTC), Schedule: "weekend"},
+	}, runs)
+}
+
+func TestScheduleInstanceForPod(t *testing.T) {
+	instance := newMultiSchedulePSC("daily")
+	primerAt := time.Date(2020, 1, 31, 7, 45, 0, 0, time.UTC)
+	jobName := fmt.Sprintf("autogen-daily-weekday-%d", primerAt.Unix())
+	pod := newPrimedPod(jobName, jobName+"-abcde")
+	pod.Labels[ScheduleLabel] = "weekday"
+
+	view, err := ScheduleInstanceForPod(&instance, pod)
+	require.NoError(t, err)
+	workloadAt, err := workloadTimeForPod(pod, view)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2020, 1, 31, 8, 0, 0, 0, time.UTC), workloadAt)
+
+	pod.Labels[ScheduleLabel] = "holiday"
+	_, err = ScheduleInstanceForPod(&instance, pod)
+	require.Error(t, err)
+
+	delete(pod.Labels, ScheduleLabel)
+	_, err = ScheduleInstanceForPod(&instance, pod)
+	require.Error(t, err)
+}
+
+func TestReconcile_CreatesACronJobPerSchedule(t *testing.T) {
+	instance := newMultiSchedulePSC("daily")
+	instance.UID = types.UID("psc-uid")
+	instance.Finalizers = []string{finalizerName}
+	r := newTestReconciler(t, &instance)
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	ctx := context.Background()
+
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	weekday := &batchv1beta1.CronJob{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "autogen-daily-weekday", Namespace: namespace}, weekday))
+	require.Equal(t, "45 7 * * 1-5", weekday.Spec.Schedule)
+	template := weekday.Spec.JobTemplate.Spec.Template
+	require.Equal(t, "weekday", template.Labels[ScheduleLabel])
+	require.Contains(t, template.Spec.InitContainers[0].Env, corev1.EnvVar{Name: "CRONJOB_SCHEDULE", Value: "0 8 * * 1-5"})
+	require.Contains(t, template.Spec.InitContainers[0].Env, corev1.EnvVar{Name: "SCHEDULE_NAME", Value: "weekday"})
+
+	weekend := &batchv1beta1.CronJob{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "autogen-daily-weekend", Namespace: namespace}, weekend))
+	require.Equal(t, "50 9 * * 0,6", weekend.Spec.Schedule)
+
+	// dropping a schedule removes its cronjob
+	fetched := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(ctx, key, fetched))
+	fetched.Spec.Schedules = fetched.Spec.Schedules[:1]
+	require.NoError(t, r.Update(ctx, fetched))
+	_, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	err = r.Get(ctx, types.NamespacedName{Name: "autogen-daily-weekend", Namespace: namespace}, &batchv1beta1.CronJob{})
+	require.True(t, errors.IsNotFound(err))
+	crons := &batchv1beta1.CronJobList{}
+	require.NoError(t, r.List(ctx, crons, client.InNamespace(namespace)))
+	require.Len(t, crons.Items, 1)
+	require.Equal(t, "autogen-daily-weekday", crons.Items[0].Name)
+}
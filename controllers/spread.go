@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// primerOffset is how much earlier than its warm-up needs a prescaledcronjob's primer fires, spreading the
+// primers of objects sharing a schedule over the minutes of the PrimerSpread. The offset is seeded by the UID so
+// it stays the same across reconciles and operator restarts, objects without one yet aren't offset.
+func primerOffset(instance *pscv1alpha1.PreScaledCronJob) time.Duration {
+	if instance.Spec.PrimerSpread == nil || instance.Spec.PrimerSchedule != "" || instance.UID == "" {
+		return 0
+	}
+
+	minutes := uint32(instance.Spec.PrimerSpread.Duration / time.Minute)
+	if minutes == 0 {
+		return 0
+	}
+
+	hash := fnv.New32a()
+	// writing to a hash never fails
+	_, _ = hash.Write([]byte(instance.UID))
+	return time.Duration(hash.Sum32()%minutes) * time.Minute
+}
+
+// updatePrimerOffset records the offset chosen for the primer in status
+func (r *PreScaledCronJobReconciler) updatePrimerOffset(ctx context.Context, instance *pscv1alpha1.PreScaledCronJob) error {
+	var offset *metav1.Duration
+	if instance.Spec.PrimerSpread != nil && instance.Spec.PrimerSchedule == "" {
+		offset = &metav1.Duration{Duration: primerOffset(instance)}
+	}
+
+	current := instance.Status.PrimerOffset
+	if (offset == nil && current == nil) || (offset != nil && current != nil && offset.Duration == current.Duration) {
+		return nil
+	}
+
+	instance.Status.PrimerOffset = offset
+	return r.Status().Update(ctx, instance)
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestPrimerOffset_IsStableAndWithinTheSpread(t *testing.T) {
+	instance := newPreviewPSC("nightly", "0 0 * * *", 10, "")
+	instance.Spec.PrimerSpread = &metav1.Duration{Duration: time.Minute * 15}
+
+	offsets := map[time.Duration]bool{}
+	for i := 0; i < 50; i++ {
+		instance.UID = types.UID(fmt.Sprintf("uid-%d", i))
+		offset := primerOffset(&instance)
+		require.Equal(t, offset, primerOffset(&instance))
+		require.True(t, offset >= 0 && offset < time.Minute*15, offset.String())
+		require.Zero(t, offset%time.Minute)
+		offsets[offset] = true
+	}
+	require.True(t, len(offsets) > 1, "offsets should differ between objects")
+
+	// nothing to seed the offset from yet
+	instance.UID = ""
+	require.Zero(t, primerOffset(&instance))
+
+	// an explicit primer schedule isn't moved
+	instance.UID = "uid-1"
+	instance.Spec.PrimerSchedule = "50 23 * * *"
+	require.Zero(t, primerOffset(&instance))
+}
+
+func TestPrimerScheduleFor_AppliesTheOffset(t *testing.T) {
+	instance := newPreviewPSC("nightly", "0 0 * * *", 10, "")
+	instance.UID = "uid-1"
+	instance.Spec.PrimerSpread = &metav1.Duration{Duration: time.Minute * 30}
+	offset := primerOffset(&instance)
+
+	primerSchedule, err := PrimerScheduleFor(&instance)
+	require.NoError(t, err)
+
+	minutes := int((time.Minute*10 + offset) / time.Minute)
+	require.Equal(t, fmt.Sprintf("%d 23 * * *", 60-minutes), primerSchedule)
+
+	// the workload is still released on the hour
+	runs, err := UpcomingRuns(&instance, time.Date(2020, 1, 29, 12, 0, 0, 0, time.UTC), time.Date(2020, 1, 30, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.Equal(t, time.Date(2020, 1, 30, 0, 0, 0, 0, time.UTC), runs[0].WorkloadAt)
+}
+
+func TestReconcile_RecordsThePrimerOffset(t *testing.T) {
+	instance := generatePSCSpec()
+	instance.UID = "uid-1"
+	instance.Finalizers = []string{finalizerName}
+	instance.Spec.PrimerSpread = &metav1.Duration{Duration: time.Minute * 20}
+	r := newTestReconciler(t, &instance)
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	fetched := &pscv1alpha1.PreScaledCronJob{}
+	require.NoError(t, r.Get(context.Background(), key, fetched))
+	require.NotNil(t, fetched.Status.PrimerOffset)
+	require.Equal(t, primerOffset(&instance), fetched.Status.PrimerOffset.Duration)
+}
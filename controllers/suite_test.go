@@ -76,6 +76,7 @@ var _ = BeforeSuite(func(done Done) {
 	/*
 		err = (&PreScaledCronJobReconciler{
 			Client:             k8sManager.GetClient(),
+			Scheme:             k8sManager.GetScheme(),
 			Log:                ctrl.Log.WithName("controllers").WithName("PrescaledCronJob"),
 			Recorder:           k8sManager.GetEventRecorderFor("prescaledcronjob-controller"),
 			InitContainerImage: "initcontainer:1",
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	configv1alpha1 "cronprimer.local/api/config/v1alpha1"
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	tracerName  = "cronprimer.local/controllers"
+	serviceName = "prescaledcronjob-operator"
+
+	runSpan           = "prescaled-run"
+	podCreationSpan   = "pod-creation"
+	schedulingSpan    = "scheduling"
+	warmUpSpan        = "warm-up"
+	imagePullSpan     = "image-pull"
+	workloadStartSpan = "workload-start"
+
+	prescaledCronJobKey = attribute.Key("psc.prescaledcronjob")
+	nodepoolKey         = attribute.Key("psc.nodepool")
+	scheduleKey         = attribute.Key("psc.schedule")
+	workloadDelayKey    = attribute.Key("psc.workload.delay_seconds")
+)
+
+// NewTracerProvider sets up the export of run traces to an OTLP/HTTP collector, the provider has to be shut
+// down to send the last traces
+func NewTracerProvider(ctx context.Context, config configv1alpha1.TracingConfig) (*sdktrace.TracerProvider, error) {
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.Endpoint)}
+	if config.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	), nil
+}
+
+// RunTracer is the tracer the pod reconciler records runs with
+func RunTracer(provider trace.TracerProvider) trace.Tracer {
+	return provider.Tracer(tracerName)
+}
+
+// traceRun records a primed pod's run as a trace once its workload has started: a root span from the primer
+// firing to the workload starting, with a child span for each transition which was seen. The times come from
+// the pod's events, so the spans are recorded after the fact.
+func traceRun(tracer trace.Tracer, timings podTransitionTimes, pod *corev1.Pod, instance *pscv1alpha1.PreScaledCronJob, workloadAt time.Time) {
+	if tracer == nil || !timings.workloadStartedNow {
+		return
+	}
+
+	startedAt := timings.workloadStartAt.LastTimestamp.Time
+	createdAt := timings.createdAt.Time
+	primerAt, err := PrimerTimeFromJobName(autogenName(instance), jobNameFor(pod))
+	if err != nil {
+		// a job created by hand rather than by the primer, the run starts with its pod
+		primerAt = createdAt
+	}
+
+	attributes := []attribute.KeyValue{
+		prescaledCronJobKey.String(instance.Name),
+		semconv.K8SNamespaceNameKey.String(instance.Namespace),
+		nodepoolKey.String(nodepoolFor(&pod.Spec)),
+		semconv.K8SPodNameKey.String(pod.Name),
+		semconv.K8SJobNameKey.String(jobNameFor(pod)),
+	}
+	if name := ScheduleName(instance); name != "" {
+		attributes = append(attributes, scheduleKey.String(name))
+	}
+
+	ctx, root := tracer.Start(context.Background(), runSpan, trace.WithTimestamp(primerAt), trace.WithAttributes(attributes...))
+	defer root.End(trace.WithTimestamp(startedAt))
+
+	if err == nil {
+		childSpan(ctx, tracer, podCreationSpan, primerAt, createdAt)
+	}
+	if timings.scheduledAt != nil {
+		childSpan(ctx, tracer, schedulingSpan, createdAt, timings.scheduledAt.LastTimestamp.Time)
+	}
+	if timings.initStartAt != nil && timings.initFinishedAt != nil {
+		childSpan(ctx, tracer, warmUpSpan, timings.initStartAt.LastTimestamp.Time, timings.initFinishedAt.LastTimestamp.Time)
+	}
+	if timings.initFinishedAt != nil {
+		childSpan(ctx, tracer, imagePullSpan, timings.initFinishedAt.LastTimestamp.Time, startedAt)
+	}
+
+	// the workload start spans the delay past the workload time, a workload which started early has no delay
+	if !workloadAt.IsZero() {
+		from := workloadAt
+		if startedAt.Before(from) {
+			from = startedAt
+		}
+		childSpan(ctx, tracer, workloadStartSpan, from, startedAt, workloadDelayKey.Float64(startedAt.Sub(workloadAt).Seconds()))
+	}
+}
+
+func childSpan(ctx context.Context, tracer trace.Tracer, name string, from time.Time, to time.Time, attributes ...attribute.KeyValue) {
+	_, span := tracer.Start(ctx, name, trace.WithTimestamp(from), trace.WithAttributes(attributes...))
+	span.End(trace.WithTimestamp(to))
+}
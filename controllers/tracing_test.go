@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	configv1alpha1 "cronprimer.local/api/config/v1alpha1"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// lateCreatedRun is the run of the late-created event fixtures, its primer fired at 12:00 for a 12:05 workload
+func lateCreatedRun(t *testing.T) (podTransitionTimes, *corev1.Pod, time.Time) {
+	instance := newPreviewPSC("psc-late", "5/30 * * * *", 0, "")
+	instance.Spec.PrimerSchedule = "*/30 * * * *"
+	primerAt := time.Date(2020, 1, 29, 12, 0, 0, 0, time.UTC)
+	jobName := fmt.Sprintf("%s-%d", autogenName(&instance), primerAt.Unix())
+	pod := newPrimedPod(jobName, jobName+"-x7k2p")
+	pod.CreationTimestamp = metav1.NewTime(time.Date(2020, 1, 29, 12, 7, 0, 0, time.UTC))
+
+	events := []corev1.Event{}
+	newEvents := map[types.UID]corev1.Event{}
+	for _, name := range []string{"workloadStartedEvent", "workloadPulledEvent", "initStartedEvent", "scheduledEvent"} {
+		event := mustReadEventFromFile("../testdata/events/late-created/" + name + ".json")
+		require.Equal(t, pod.Name, event.InvolvedObject.Name)
+		events = append(events, event)
+		newEvents[event.UID] = event
+	}
+
+	workloadAt := time.Date(2020, 1, 29, 12, 5, 0, 0, time.UTC)
+	timings, err := generateTransitionTimingsFromEvents(events, newEvents, pod.CreationTimestamp, workloadAt)
+	require.NoError(t, err)
+	return timings, pod, workloadAt
+}
+
+func TestTraceRun_SpansEachTransition(t *testing.T) {
+	timings, pod, workloadAt := lateCreatedRun(t)
+	instance := newPreviewPSC("psc-late", "5/30 * * * *", 0, "")
+	instance.Spec.PrimerSchedule = "*/30 * * * *"
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	traceRun(RunTracer(provider), timings, pod, &instance, workloadAt)
+
+	at := func(minute int, second int) time.Time {
+		return time.Date(2020, 1, 29, 12, minute, second, 0, time.UTC)
+	}
+	expected := map[string][2]time.Time{
+		runSpan:           {at(0, 0), at(8, 10)},
+		podCreationSpan:   {at(0, 0), at(7, 0)},
+		schedulingSpan:    {at(7, 0), at(7, 2)},
+		warmUpSpan:        {at(7, 4), at(8, 6)},
+		imagePullSpan:     {at(8, 6), at(8, 10)},
+		workloadStartSpan: {at(5, 0), at(8, 10)},
+	}
+
+	spans := recorder.Ended()
+	require.Len(t, spans, len(expected))
+	var root sdktrace.ReadOnlySpan
+	for _, span := range spans {
+		times, exists := expected[span.Name()]
+		require.True(t, exists, span.Name())
+		require.Equal(t, times[0], span.StartTime().UTC(), span.Name())
+		require.Equal(t, times[1], span.EndTime().UTC(), span.Name())
+		if span.Name() == runSpan {
+			root = span
+		}
+	}
+
+	require.NotNil(t, root)
+	require.Contains(t, root.Attributes(), prescaledCronJobKey.String("psc-late"))
+	require.Contains(t, root.Attributes(), nodepoolKey.String(noNodepool))
+	for _, span := range spans {
+		if span != root {
+			require.Equal(t, root.SpanContext().SpanID(), span.Parent().SpanID(), span.Name())
+		}
+	}
+
+	// a run is traced once, when its workload starts
+	timings.workloadStartedNow = false
+	traceRun(RunTracer(provider), timings, pod, &instance, workloadAt)
+	require.Len(t, recorder.Ended(), len(expected))
+}
+
+func TestNewTracerProvider_ExportsToTheCollector(t *testing.T) {
+	received := make(chan *coltracepb.ExportTraceServiceRequest, 10)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		request := &coltracepb.ExportTraceServiceRequest{}
+		require.NoError(t, proto.Unmarshal(body, request))
+		received <- request
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	ctx := context.Background()
+	provider, err := NewTracerProvider(ctx, configv1alpha1.TracingConfig{
+		Endpoint: strings.TrimPrefix(collector.URL, "http://"),
+		Insecure: true,
+	})
+	require.NoError(t, err)
+
+	timings, pod, workloadAt := lateCreatedRun(t)
+	instance := newPreviewPSC("psc-late", "5/30 * * * *", 0, "")
+	instance.Spec.PrimerSchedule = "*/30 * * * *"
+	traceRun(RunTracer(provider), timings, pod, &instance, workloadAt)
+	require.NoError(t, provider.Shutdown(ctx))
+
+	names := []string{}
+	for len(received) > 0 {
+		request := <-received
+		for _, resourceSpans := range request.ResourceSpans {
+			require.Contains(t, resourceSpans.Resource.String(), serviceName)
+			for _, librarySpans := range resourceSpans.InstrumentationLibrarySpans {
+				for _, span := range librarySpans.Spans {
+					names = append(names, span.Name)
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	require.Equal(t, []string{imagePullSpan, podCreationSpan, runSpan, schedulingSpan, warmUpSpan, workloadStartSpan}, names)
+}
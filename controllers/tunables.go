@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	configv1alpha1 "cronprimer.local/api/config/v1alpha1"
+)
+
+// Tunables are the operator settings which can safely change while the manager is running
+type Tunables struct {
+	// NodepoolLabel is the node selector key used to find which nodepool a pod targets
+	NodepoolLabel string
+	// EventTrackingTTL is how long the last processed event of a pod is remembered
+	EventTrackingTTL time.Duration
+	// Cost prices the node time reserved by warm-up pods, nothing is priced when it has no prices
+	Cost configv1alpha1.CostConfig
+}
+
+var (
+	tunablesLock sync.RWMutex
+	tunables     = defaultTunables()
+)
+
+// defaultTunables are the tunables of an operator configuration with nothing set, so the defaults live in one place
+func defaultTunables() Tunables {
+	config := &configv1alpha1.OperatorConfig{}
+	configv1alpha1.SetDefaults(config)
+	return TunablesFromConfig(config)
+}
+
+// SetTunables replaces the tunables used by the controllers
+func SetTunables(t Tunables) {
+	tunablesLock.Lock()
+	defer tunablesLock.Unlock()
+	tunables = t
+}
+
+func currentTunables() Tunables {
+	tunablesLock.RLock()
+	defer tunablesLock.RUnlock()
+	return tunables
+}
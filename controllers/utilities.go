@@ -2,10 +2,13 @@ package controllers
 
 import (
 	"fmt"
-	"strconv"
-	"strings"
+	"sort"
+	"time"
 
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
 	"github.com/robfig/cron/v3"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // GetPrimerSchedule tries to parse (an optional) primerSchedule and otherwise manually creates the primerSchedule
@@ -24,92 +27,181 @@ func GetPrimerSchedule(scheduleSpec string, warmupMinutes int, primerSchedule st
 	return CreatePrimerSchedule(scheduleSpec, warmupMinutes)
 }
 
-// CreatePrimerSchedule deducts the warmup time from the original cronjob schedule and creates a primed cronjob schedule
-func CreatePrimerSchedule(scheduleSpec string, warmupMinutes int) (string, error) {
-	// bool that will be set to true if the minute expression goes < 0 after deduction
-	// therefore the hour expression will also need adjustments if not set to *
-	negativeMinutes := false
-
-	// parse schedule
-	_, err := cron.ParseStandard(scheduleSpec)
+// PrimerScheduleFor returns the primer schedule the operator will use for a prescaledcronjob, including the offset
+// of its primer spread. Prescaledcronjobs with several schedules are passed through ScheduleInstances first.
+func PrimerScheduleFor(instance *pscv1alpha1.PreScaledCronJob) (string, error) {
+	instance, err := singleSchedule(instance)
 	if err != nil {
-		return "", fmt.Errorf("scheduleSpec provided is invalid: %v", err)
+		return "", err
 	}
 
-	cronArray := strings.Split(scheduleSpec, " ")
+	warmUp := WarmUpFor(instance)
+	if warmUp < 0 {
+		return "", fmt.Errorf("warm-up of %s can't be negative", warmUp)
+	}
+	return GetPrimerSchedule(instance.Spec.CronJob.Spec.Schedule, WarmUpMinutes(warmUp+primerOffset(instance)), instance.Spec.PrimerSchedule)
+}
 
-	// validate cronjob
-	if cronArray[0] == "*" {
-		err = fmt.Errorf("Can't create primer schedule on something that runs every minute")
-		return "", err
+// WarmUpFor returns the warm-up of a prescaledcronjob, converting the deprecated warmUpTimeMins when
+// warmUpDuration is unset
+func WarmUpFor(instance *pscv1alpha1.PreScaledCronJob) time.Duration {
+	if instance.Spec.WarmUpDuration != nil {
+		return instance.Spec.WarmUpDuration.Duration
 	}
+	return time.Duration(instance.Spec.WarmUpTimeMins) * time.Minute
+}
 
-	// convert cron expressions with step (/) to commas
-	if strings.Contains(cronArray[0], "/") {
-		cronArray, err = convertStepCronToCommaCron(cronArray)
+// WarmUpMinutes rounds a warm-up up to whole minutes, as cron can't fire a primer between minutes. The primer
+// fires early by the difference, the workload is still released at its scheduled time.
+func WarmUpMinutes(warmUp time.Duration) int {
+	return int((warmUp + time.Minute - 1) / time.Minute)
+}
 
-		if err != nil {
-			return "", err
+// NextFireTimes returns the next count times a cron schedule will fire after from
+func NextFireTimes(scheduleSpec string, from time.Time, count int) ([]time.Time, error) {
+	schedule, err := cron.ParseStandard(scheduleSpec)
+	if err != nil {
+		return nil, fmt.Errorf("schedule provided is invalid: %v", err)
+	}
+
+	times := make([]time.Time, 0, count)
+	next := from
+	for i := 0; i < count; i++ {
+		next = schedule.Next(next)
+		if next.IsZero() {
+			break
 		}
+		times = append(times, next)
 	}
 
-	// convert cron expressions with range (-) to commas
-	if strings.Contains(cronArray[0], "-") {
-		cronArray, err = convertRangeCronToCommaCron(cronArray)
+	return times, nil
+}
+
+// PrimedRun is a single warm-up of a prescaledcronjob and the workload run it is warming up for
+type PrimedRun struct {
+	PrimerAt   time.Time `json:"primerAt"`
+	WorkloadAt time.Time `json:"workloadAt"`
+	// Schedule is the name of the schedule entry the run belongs to, empty without schedules
+	Schedule string `json:"schedule,omitempty"`
+}
+
+// UpcomingRuns returns the primed runs of a prescaledcronjob whose primer fires between from and until, the
+// runs of several schedules are ordered by primer time. Like the warm-up container, each primer is paired with
+// the first workload run after it fires.
+func UpcomingRuns(instance *pscv1alpha1.PreScaledCronJob, from time.Time, until time.Time) ([]PrimedRun, error) {
+	views := ScheduleInstances(instance)
+	if len(views) == 1 {
+		return scheduleRuns(views[0], from, until)
+	}
 
+	runs := []PrimedRun{}
+	for _, view := range views {
+		scheduled, err := scheduleRuns(view, from, until)
 		if err != nil {
-			return "", err
+			return nil, fmt.Errorf("schedule %s: %s", ScheduleName(view), err)
 		}
+		runs = append(runs, scheduled...)
 	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].PrimerAt.Before(runs[j].PrimerAt)
+	})
+	return runs, nil
+}
 
-	if strings.Contains(cronArray[0], ",") {
-		commaValues := strings.Split(cronArray[0], ",")
+// scheduleRuns returns the primed runs of a single schedule
+func scheduleRuns(instance *pscv1alpha1.PreScaledCronJob, from time.Time, until time.Time) ([]PrimedRun, error) {
+	instance, err := singleSchedule(instance)
+	if err != nil {
+		return nil, err
+	}
 
-		for i, s := range commaValues {
-			commaValues[i], negativeMinutes, err = deductWarmupMinutes(s, warmupMinutes)
+	primerSchedule, err := PrimerScheduleFor(instance)
+	if err != nil {
+		return nil, err
+	}
 
-			if err != nil {
-				return "", err
-			}
+	primer, err := cron.ParseStandard(primerSchedule)
+	if err != nil {
+		return nil, err
+	}
+	workload, err := cron.ParseStandard(instance.Spec.CronJob.Spec.Schedule)
+	if err != nil {
+		return nil, err
+	}
 
-			if negativeMinutes && cronArray[1] != "*" {
-				return "", fmt.Errorf("Can't adjust hour for minute expression with multiple values")
-			}
-		}
+	runs := []PrimedRun{}
+	for primerAt := primer.Next(from); !primerAt.IsZero() && !primerAt.After(until); primerAt = primer.Next(primerAt) {
+		runs = append(runs, PrimedRun{PrimerAt: primerAt, WorkloadAt: workload.Next(primerAt), Schedule: ScheduleName(instance)})
+	}
 
-		cronArray[0] = strings.Join(commaValues, ",")
+	return runs, nil
+}
 
-	} else {
-		cronArray[0], negativeMinutes, err = deductWarmupMinutes(cronArray[0], warmupMinutes)
-		if err != nil {
-			return "", err
-		}
+// ConvertCronJob wraps an existing cronjob in a prescaledcronjob, failing if no primer schedule can be made for it
+func ConvertCronJob(cronJob *batchv1beta1.CronJob, warmupMinutes int, primerSchedule string) (*pscv1alpha1.PreScaledCronJob, error) {
+	instance := &pscv1alpha1.PreScaledCronJob{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: pscv1alpha1.GroupVersion.String(),
+			Kind:       "PreScaledCronJob",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        cronJob.Name,
+			Namespace:   cronJob.Namespace,
+			Labels:      cronJob.Labels,
+			Annotations: cronJob.Annotations,
+		},
+		Spec: pscv1alpha1.PreScaledCronJobSpec{
+			WarmUpTimeMins: warmupMinutes,
+			PrimerSchedule: primerSchedule,
+			CronJob: batchv1beta1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: cronJob.Name,
+				},
+				Spec: *cronJob.Spec.DeepCopy(),
+			},
+		},
+	}
 
-		if negativeMinutes && cronArray[1] != "*" {
-			hourCron, minErr := strconv.Atoi(cronArray[1])
-			if minErr != nil {
-				return "", fmt.Errorf("Can't adjust special characters in cron-hour argument")
-			}
+	if _, err := PrimerScheduleFor(instance); err != nil {
+		return nil, err
+	}
 
-			// adjust hour param (if not set to *)
-			cronArray[1] = strconv.Itoa(hourCron - 1)
+	return instance, nil
+}
 
-			// only allow changing a 'negative' hour when the cron expression is not day-specific
-			if cronArray[1] == "-1" && cronArray[2] == "*" && cronArray[3] == "*" && cronArray[4] == "*" {
-				cronArray[1] = "23"
-			} else if cronArray[1] == "-1" {
-				// cronjobs that run on midnight on a specific day are not supported
-				return "", fmt.Errorf("Unsupported cron, can't create primer cronjob with this expression")
-			}
-		} else if negativeMinutes &&
-			(cronArray[2] != "*" || cronArray[3] != "*" || cronArray[4] != "*") {
-			// cronjobs that run on midnight on a specific day are not supported
-			return "", fmt.Errorf("Unsupported cron, can't create primer cronjob with this expression")
-		}
+// minutesPerDay bounds the warmup, a primer a day or more ahead of its run can't be told apart from one for an
+// earlier run
+const minutesPerDay = 24 * 60
+
+// CreatePrimerSchedule deducts the warmup time from the original cronjob schedule and creates a primed cronjob schedule
+func CreatePrimerSchedule(scheduleSpec string, warmupMinutes int) (string, error) {
+	// parse schedule
+	schedule, err := ParseCronSchedule(scheduleSpec)
+	if err != nil {
+		return "", fmt.Errorf("scheduleSpec provided is invalid: %v", err)
+	}
+	if _, err := cron.ParseStandard(scheduleSpec); err != nil {
+		return "", fmt.Errorf("scheduleSpec provided is invalid: %v", err)
+	}
+
+	// validate cronjob
+	if schedule.Every != 0 {
+		return "", fmt.Errorf("Can't create primer schedule on an @every schedule, it doesn't run at fixed times")
+	}
+	if schedule.Fields[MinuteField].covers() {
+		return "", fmt.Errorf("Can't create primer schedule on something that runs every minute")
+	}
+	if warmupMinutes < 0 || warmupMinutes >= minutesPerDay {
+		return "", fmt.Errorf("warmup time must be between 0 and %d minutes", minutesPerDay-1)
+	}
+
+	primer, err := deductWarmup(schedule, warmupMinutes)
+	if err != nil {
+		return "", err
 	}
 
 	// parse primer schedule
-	primerSchedule := strings.Join(cronArray, " ")
+	primerSchedule := primer.String()
 	_, err = cron.ParseStandard(primerSchedule)
 	if err != nil {
 		return "", err
@@ -118,69 +210,133 @@ func CreatePrimerSchedule(scheduleSpec string, warmupMinutes int) (string, error
 	return primerSchedule, nil
 }
 
-func convertStepCronToCommaCron(cronArray []string) ([]string, error) {
-	splitStepVal := strings.Split(cronArray[0], "/")
-
-	// convert */x to 0/x since it's the same but easier to work with
-	if splitStepVal[0] == "*" {
-		splitStepVal[0] = "0"
+// deductWarmup moves every run of the schedule back by the warmup. Each field is shifted in turn, borrowing from
+// the next one when it goes below zero. A borrow is only possible when it is the same for every value of the field,
+// or when the field it borrows from matches everything so the borrow doesn't change it.
+func deductWarmup(schedule *CronSchedule, warmupMinutes int) (*CronSchedule, error) {
+	primer := *schedule
+	// descriptors are written out as fields, as the primer rarely matches one
+	primer.Descriptor = ""
+
+	minutes, borrowAll, borrowSome := shiftValues(schedule.Fields[MinuteField].Values(), warmupMinutes%60, 60)
+	if warmupMinutes%60 != 0 {
+		primer.Fields[MinuteField] = cronFieldOf(MinuteField, minutes)
 	}
 
-	startVal, err1 := strconv.Atoi(splitStepVal[0])
-	stepVal, err2 := strconv.Atoi(splitStepVal[1])
-	if err1 != nil || err2 != nil {
-		return nil, fmt.Errorf("Can't break up step values")
+	everyHour := schedule.Fields[HourField].covers()
+	everyDay := runsEveryDay(schedule)
+	// several minutes in every hour keep their days, as they always have, so on restricted days the runs in the
+	// first minutes after midnight are primed from the minutes before the next hour rather than the day before
+	everyHourOfTheDays := everyHour && len(schedule.Fields[MinuteField].Values()) > 1 && warmupMinutes < 60
+	if borrowSome && !borrowAll {
+		if !everyHour {
+			return nil, fmt.Errorf("Can't adjust hour for minute expression with multiple values")
+		}
+		return &primer, nil
 	}
 
-	cronArray[0] = splitStepVal[0] + ","
-
-	for startVal+stepVal < 60 {
-		startVal += stepVal
-		cronArray[0] += strconv.Itoa(startVal) + ","
+	hourShift := warmupMinutes / 60
+	if borrowAll {
+		hourShift++
+	}
+	if hourShift == 0 {
+		return &primer, nil
+	}
+	if everyHour {
+		// the hours stay the same but the runs in the first hours move to the day before
+		if !everyDay && !everyHourOfTheDays {
+			return nil, fmt.Errorf("Unsupported cron, can't create primer cronjob with this expression")
+		}
+		return &primer, nil
 	}
 
-	// remove trailing comma
-	cronArray[0] = strings.TrimSuffix(cronArray[0], ",")
+	hours, borrowAll, borrowSome := shiftValues(schedule.Fields[HourField].Values(), hourShift, 24)
+	primer.Fields[HourField] = cronFieldOf(HourField, hours)
+	if !borrowSome || everyDay {
+		return &primer, nil
+	}
+	if !borrowAll {
+		// cronjobs that run on midnight on a specific day are not supported
+		return nil, fmt.Errorf("Unsupported cron, can't create primer cronjob with this expression")
+	}
 
-	return cronArray, nil
+	if err := deductDay(schedule, &primer); err != nil {
+		return nil, err
+	}
+	return &primer, nil
 }
 
-func convertRangeCronToCommaCron(cronArray []string) ([]string, error) {
-	rangeVal := strings.Split(cronArray[0], "-")
-	startVal, err1 := strconv.Atoi(rangeVal[0])
-	endVal, err2 := strconv.Atoi(rangeVal[1])
-	if err1 != nil || err2 != nil {
-		return nil, fmt.Errorf("Can't break up range values")
+// shiftValues deducts shift from each value, wrapping around at size, and reports whether all or some of them wrapped
+func shiftValues(values []int, shift int, size int) (shifted []int, wrappedAll bool, wrappedSome bool) {
+	wrappedAll = true
+	for _, value := range values {
+		value -= shift
+		if value < 0 {
+			value += size
+			wrappedSome = true
+		} else {
+			wrappedAll = false
+		}
+		shifted = append(shifted, value)
 	}
+	return shifted, wrappedAll && wrappedSome, wrappedSome
+}
 
-	cronArray[0] = rangeVal[0] + ","
-
-	for startVal+1 <= endVal {
-		startVal++
-		cronArray[0] += strconv.Itoa(startVal) + ","
+// runsEveryDay reports whether the day fields of a schedule match every day. Like the cron library, a restricted
+// day-of-month and day-of-week match a day when either does, but when one of them is a star both need to match.
+func runsEveryDay(schedule *CronSchedule) bool {
+	if !schedule.Fields[MonthField].covers() {
+		return false
+	}
+	dom, dow := schedule.Fields[DayOfMonthField], schedule.Fields[DayOfWeekField]
+	if dom.IsStar() || dow.IsStar() {
+		return dom.covers() && dow.covers()
 	}
+	return dom.covers() || dow.covers()
+}
 
-	// remove trailing comma
-	cronArray[0] = strings.TrimSuffix(cronArray[0], ",")
+// daysInMonth is the shortest each month can be
+var daysInMonth = [...]int{1: 31, 2: 28, 3: 31, 4: 30, 5: 31, 6: 30, 7: 31, 8: 31, 9: 30, 10: 31, 11: 30, 12: 31}
 
-	return cronArray, nil
-}
+// deductDay moves the day fields of a schedule back a day, which only works when the day before a run is always
+// in the same month or any month may be used
+func deductDay(schedule *CronSchedule, primer *CronSchedule) error {
+	dom, dow := schedule.Fields[DayOfMonthField], schedule.Fields[DayOfWeekField]
+	everyMonth := schedule.Fields[MonthField].covers()
 
-func deductWarmupMinutes(minuteVal string, warmupMinutes int) (string, bool, error) {
-	negativeMinutes := false
-	minuteCron, err := strconv.Atoi(minuteVal)
-	if err != nil {
-		return "", negativeMinutes, fmt.Errorf("Can't parse minute value to int")
+	// the first of an allowed month is a run day, and its primer would fire in a month that isn't allowed
+	if !everyMonth && (dom.IsStar() || !dow.IsStar()) {
+		return fmt.Errorf("Unsupported cron, can't create primer cronjob on the first day of a month with this expression")
+	}
+
+	if !dow.IsStar() {
+		weekdays, _, _ := shiftValues(dow.Values(), 1, 7)
+		primer.Fields[DayOfWeekField] = cronFieldOf(DayOfWeekField, weekdays)
 	}
 
-	minuteVal = strconv.Itoa(minuteCron - warmupMinutes)
+	if !dom.IsStar() {
+		days := sortedCopy(dom.Values())
+		if days[0] == 1 {
+			return fmt.Errorf("Unsupported cron, can't create primer cronjob on the first day of a month with this expression")
+		}
+
+		// the primer has to fire the day before every run and never on a day without one
+		shortest := daysInMonth[2]
+		if !everyMonth {
+			shortest = daysInMonth[1]
+			for _, month := range schedule.Fields[MonthField].Values() {
+				if daysInMonth[month] < shortest {
+					shortest = daysInMonth[month]
+				}
+			}
+		}
+		if days[len(days)-1] > shortest {
+			return fmt.Errorf("Unsupported cron, can't create primer cronjob on a day some months don't have")
+		}
 
-	// when cronjob-minute param minus warmupTime min is smaller than 0
-	if (minuteCron - warmupMinutes) < 0 {
-		// add 60 (so that e.g. -5 becomes 55)
-		minuteVal = strconv.Itoa(minuteCron - warmupMinutes + 60)
-		negativeMinutes = true
+		monthDays, _, _ := shiftValues(dom.Values(), 1, 32)
+		primer.Fields[DayOfMonthField] = cronFieldOf(DayOfMonthField, monthDays)
 	}
 
-	return minuteVal, negativeMinutes, nil
+	return nil
 }
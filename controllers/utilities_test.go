@@ -3,9 +3,12 @@ package controllers
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
@@ -144,6 +147,105 @@ func TestCreatePrimerSchedule(t *testing.T) {
 				ExpectedCronjob: "55,2,38,46 * * * 5",
 			},
 		},
+		{
+			ScenarioName: "valid cron with a stepped minute range",
+			ScenarioType: AdmissionAllowed,
+			inputData: InputData{
+				Cronjob:    "5-50/15 3 * * *",
+				WarmupTime: 5,
+			},
+			expected: Expected{
+				ExpectedCronjob: "0,15,30,45 3 * * *",
+			},
+		},
+		{
+			ScenarioName: "valid cron with names and repeated spaces",
+			ScenarioType: AdmissionAllowed,
+			inputData: InputData{
+				Cronjob:    "30  9 * jan-mar  MON-FRI",
+				WarmupTime: 10,
+			},
+			expected: Expected{
+				ExpectedCronjob: "20 9 * jan-mar MON-FRI",
+			},
+		},
+		{
+			ScenarioName: "valid descriptor that needs to adjust the day-of-the-week",
+			ScenarioType: AdmissionAllowed,
+			inputData: InputData{
+				Cronjob:    "@weekly",
+				WarmupTime: 10,
+			},
+			expected: Expected{
+				ExpectedCronjob: "50 23 * * 6",
+			},
+		},
+		{
+			ScenarioName: "valid descriptor with a time zone",
+			ScenarioType: AdmissionAllowed,
+			inputData: InputData{
+				Cronjob:    "CRON_TZ=Europe/Amsterdam @hourly",
+				WarmupTime: 15,
+			},
+			expected: Expected{
+				ExpectedCronjob: "CRON_TZ=Europe/Amsterdam 45 * * * *",
+			},
+		},
+		{
+			ScenarioName: "valid warmup longer than an hour",
+			ScenarioType: AdmissionAllowed,
+			inputData: InputData{
+				Cronjob:    "15 8 * * *",
+				WarmupTime: 90,
+			},
+			expected: Expected{
+				ExpectedCronjob: "45 6 * * *",
+			},
+		},
+		{
+			ScenarioName: "valid cron that needs to adjust the day-of-the-month",
+			ScenarioType: AdmissionAllowed,
+			inputData: InputData{
+				Cronjob:    "0 0 15 * *",
+				WarmupTime: 30,
+			},
+			expected: Expected{
+				ExpectedCronjob: "30 23 14 * *",
+			},
+		},
+		{
+			ScenarioName: "invalid, expected cron needs to change month",
+			ScenarioType: AdmissionRejected,
+			inputData: InputData{
+				Cronjob:    "@monthly",
+				WarmupTime: 10,
+			},
+			expected: Expected{
+				ExpectedCronjob: "",
+			},
+		},
+		{
+			ScenarioName: "invalid, @every doesn't run at fixed times",
+			ScenarioType: AdmissionRejected,
+			inputData: InputData{
+				Cronjob:    "@every 1h",
+				WarmupTime: 10,
+			},
+			expected: Expected{
+				ExpectedCronjob: "",
+			},
+		},
+		{
+			ScenarioName: "invalid, last day of the month isn't supported by cronjobs",
+			ScenarioType: AdmissionRejected,
+			inputData: InputData{
+				Cronjob:    "0 12 L * *",
+				WarmupTime: 10,
+			},
+			expected: Expected{
+				ExpectedCronjob: "",
+			},
+		},
 		{
 			ScenarioName: "invalid cron (every minute)",
 			ScenarioType: AdmissionRejected,
@@ -167,25 +269,25 @@ func TestCreatePrimerSchedule(t *testing.T) {
 			},
 		},
 		{
-			ScenarioName: "can't use combination of ranges and step values",
-			ScenarioType: AdmissionRejected,
+			ScenarioName: "valid combination of ranges and step values",
+			ScenarioType: AdmissionAllowed,
 			inputData: InputData{
 				Cronjob:    "15-17,0/30 * * * *",
 				WarmupTime: 5,
 			},
 			expected: Expected{
-				ExpectedCronjob: "",
+				ExpectedCronjob: "10,11,12,55,25 * * * *",
 			},
 		},
 		{
-			ScenarioName: "can't convert special characters in cron-hour argument",
-			ScenarioType: AdmissionRejected,
+			ScenarioName: "valid cron that needs to adjust an hour range",
+			ScenarioType: AdmissionAllowed,
 			inputData: InputData{
 				Cronjob:    "0 14-16 * * *",
 				WarmupTime: 10,
 			},
 			expected: Expected{
-				ExpectedCronjob: "",
+				ExpectedCronjob: "50 13,14,15 * * *",
 			},
 		},
 		{
@@ -200,14 +302,14 @@ func TestCreatePrimerSchedule(t *testing.T) {
 			},
 		},
 		{
-			ScenarioName: "invalid, expected cron needs to change day-of-the-week",
-			ScenarioType: AdmissionRejected,
+			ScenarioName: "valid, expected cron needs to change day-of-the-week",
+			ScenarioType: AdmissionAllowed,
 			inputData: InputData{
 				Cronjob:    "0 0 * * 5",
 				WarmupTime: 10,
 			},
 			expected: Expected{
-				ExpectedCronjob: "",
+				ExpectedCronjob: "50 23 * * 4",
 			},
 		},
 		{
@@ -259,3 +361,86 @@ func TestGetPrimerSchedule_NoPrimerSchedule_InvalidSchedule_Returns_Error(t *tes
 
 	assert.Error(t, err)
 }
+
+func TestPrimerScheduleFor_WarmUpDuration_Rounds_Primer_Up_To_Whole_Minutes(t *testing.T) {
+	instance := generatePSCSpec()
+	instance.Spec.CronJob.Spec.Schedule = "30 * * * *"
+
+	scenarios := map[time.Duration]string{
+		time.Second * 90:                 "28 * * * *",
+		time.Minute*17 + time.Second*30:  "12 * * * *",
+		time.Minute * 5:                  "25 * * * *",
+		time.Hour + time.Millisecond*100: "29 23 * * *",
+	}
+	for warmUp, expected := range scenarios {
+		instance.Spec.CronJob.Spec.Schedule = "30 * * * *"
+		if warmUp > time.Hour {
+			instance.Spec.CronJob.Spec.Schedule = "30 0 * * *"
+		}
+		instance.Spec.WarmUpDuration = &metav1.Duration{Duration: warmUp}
+
+		primerSchedule, err := PrimerScheduleFor(&instance)
+		require.NoError(t, err)
+		require.Equal(t, expected, primerSchedule, warmUp.String())
+	}
+}
+
+func TestWarmUpFor_Converts_WarmUpTimeMins(t *testing.T) {
+	instance := generatePSCSpec()
+	instance.Spec.WarmUpTimeMins = 10
+	require.Equal(t, time.Minute*10, WarmUpFor(&instance))
+
+	// the duration takes precedence over the deprecated field
+	instance.Spec.WarmUpDuration = &metav1.Duration{Duration: time.Second * 90}
+	require.Equal(t, time.Second*90, WarmUpFor(&instance))
+
+	instance.Spec.WarmUpDuration = &metav1.Duration{Duration: -time.Second}
+	_, err := PrimerScheduleFor(&instance)
+	assert.Error(t, err)
+}
+
+func TestNextFireTimes_Returns_Count_Times_In_Order(t *testing.T) {
+	from := time.Date(2020, 1, 29, 12, 3, 5, 0, time.UTC)
+	times, err := NextFireTimes("*/30 * * * *", from, 3)
+
+	if assert.NoError(t, err) {
+		require.Equal(t, []time.Time{
+			time.Date(2020, 1, 29, 12, 30, 0, 0, time.UTC),
+			time.Date(2020, 1, 29, 13, 0, 0, 0, time.UTC),
+			time.Date(2020, 1, 29, 13, 30, 0, 0, time.UTC),
+		}, times)
+	}
+}
+
+func TestNextFireTimes_InvalidSchedule_Returns_Error(t *testing.T) {
+	_, err := NextFireTimes("wibble", time.Now(), 3)
+
+	assert.Error(t, err)
+}
+
+func TestConvertCronJob_Returns_PreScaledCronJob(t *testing.T) {
+	cronJob := &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "jobs"},
+		Spec:       batchv1beta1.CronJobSpec{Schedule: "0 0 * * *"},
+	}
+
+	instance, err := ConvertCronJob(cronJob, 10, "")
+
+	if assert.NoError(t, err) {
+		require.Equal(t, "nightly", instance.Name)
+		require.Equal(t, "jobs", instance.Namespace)
+		require.Equal(t, 10, instance.Spec.WarmUpTimeMins)
+		require.Equal(t, "0 0 * * *", instance.Spec.CronJob.Spec.Schedule)
+	}
+}
+
+func TestConvertCronJob_UnsupportedSchedule_Returns_Error(t *testing.T) {
+	cronJob := &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "every-minute"},
+		Spec:       batchv1beta1.CronJobSpec{Schedule: "* * * * *"},
+	}
+
+	_, err := ConvertCronJob(cronJob, 10, "")
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// warmUpNonRootUser is the nobody user, the warm-up container needs no file system access
+const warmUpNonRootUser = int64(65534)
+
+// restrictedWarmUpDefaults are the built in settings of the warm-up container, chosen so it passes the
+// restricted Pod Security profile. Operator and per-object settings are merged over them.
+func restrictedWarmUpDefaults() pscv1alpha1.WarmUpContainerTemplate {
+	allowPrivilegeEscalation := false
+	runAsNonRoot := true
+	runAsUser := warmUpNonRootUser
+	readOnlyRootFilesystem := true
+
+	return pscv1alpha1.WarmUpContainerTemplate{
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Resources: &corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("10m"),
+				corev1.ResourceMemory: resource.MustParse("64Mi"),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("128Mi"),
+			},
+		},
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+			RunAsNonRoot:             &runAsNonRoot,
+			RunAsUser:                &runAsUser,
+			ReadOnlyRootFilesystem:   &readOnlyRootFilesystem,
+			Capabilities: &corev1.Capabilities{
+				Drop: []corev1.Capability{"ALL"},
+			},
+		},
+	}
+}
+
+// warmUpContainerSettings merges the built in defaults, the operator defaults and the prescaledcronjob's own
+// template, later ones taking precedence
+func (r *PreScaledCronJobReconciler) warmUpContainerSettings(instance *pscv1alpha1.PreScaledCronJob) pscv1alpha1.WarmUpContainerTemplate {
+	settings := restrictedWarmUpDefaults()
+	settings.Image = r.InitContainerImage
+
+	mergeWarmUpTemplate(&settings, &r.WarmUpContainer)
+	if instance.Spec.WarmUpContainer != nil {
+		mergeWarmUpTemplate(&settings, instance.Spec.WarmUpContainer)
+	}
+	return settings
+}
+
+func mergeWarmUpTemplate(base *pscv1alpha1.WarmUpContainerTemplate, override *pscv1alpha1.WarmUpContainerTemplate) {
+	if override.Image != "" {
+		base.Image = override.Image
+	}
+	if override.ImagePullPolicy != "" {
+		base.ImagePullPolicy = override.ImagePullPolicy
+	}
+	if override.Resources != nil {
+		base.Resources = mergeResources(base.Resources, override.Resources)
+	}
+	if override.SecurityContext != nil {
+		base.SecurityContext = mergeSecurityContext(base.SecurityContext, override.SecurityContext)
+	}
+}
+
+func mergeResources(base *corev1.ResourceRequirements, override *corev1.ResourceRequirements) *corev1.ResourceRequirements {
+	merged := &corev1.ResourceRequirements{}
+	if base != nil {
+		base.DeepCopyInto(merged)
+	}
+
+	merged.Requests = mergeResourceList(merged.Requests, override.Requests)
+	merged.Limits = mergeResourceList(merged.Limits, override.Limits)
+	return merged
+}
+
+func mergeResourceList(base corev1.ResourceList, override corev1.ResourceList) corev1.ResourceList {
+	if len(override) == 0 {
+		return base
+	}
+
+	merged := corev1.ResourceList{}
+	for name, quantity := range base {
+		merged[name] = quantity
+	}
+	for name, quantity := range override {
+		merged[name] = quantity
+	}
+	return merged
+}
+
+func mergeSecurityContext(base *corev1.SecurityContext, override *corev1.SecurityContext) *corev1.SecurityContext {
+	merged := &corev1.SecurityContext{}
+	if base != nil {
+		base.DeepCopyInto(merged)
+	}
+	o := override.DeepCopy()
+
+	if o.Capabilities != nil {
+		merged.Capabilities = o.Capabilities
+	}
+	if o.Privileged != nil {
+		merged.Privileged = o.Privileged
+	}
+	if o.SELinuxOptions != nil {
+		merged.SELinuxOptions = o.SELinuxOptions
+	}
+	if o.RunAsUser != nil {
+		merged.RunAsUser = o.RunAsUser
+	}
+	if o.RunAsGroup != nil {
+		merged.RunAsGroup = o.RunAsGroup
+	}
+	if o.RunAsNonRoot != nil {
+		merged.RunAsNonRoot = o.RunAsNonRoot
+	}
+	if o.ReadOnlyRootFilesystem != nil {
+		merged.ReadOnlyRootFilesystem = o.ReadOnlyRootFilesystem
+	}
+	if o.AllowPrivilegeEscalation != nil {
+		merged.AllowPrivilegeEscalation = o.AllowPrivilegeEscalation
+	}
+	if o.ProcMount != nil {
+		merged.ProcMount = o.ProcMount
+	}
+	return merged
+}
+
+// addWarmUpPullSecrets adds the operator's pull secrets for the warm-up image to the pod, keeping the pod's own
+func addWarmUpPullSecrets(podSpec *corev1.PodSpec, secrets []corev1.LocalObjectReference) {
+	for _, secret := range secrets {
+		exists := false
+		for _, existing := range podSpec.ImagePullSecrets {
+			if existing.Name == secret.Name {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			podSpec.ImagePullSecrets = append(podSpec.ImagePullSecrets, secret)
+		}
+	}
+}
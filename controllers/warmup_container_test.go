@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"testing"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestGenerateCronJob_WarmUpContainerIsRestrictedByDefault(t *testing.T) {
+	instance := generatePSCSpec()
+	r := newTestReconciler(t)
+
+	cron, err := r.generateCronJob(&instance)
+	require.NoError(t, err)
+
+	podTemplate := cron.Spec.JobTemplate.Spec.Template
+	container := podTemplate.Spec.InitContainers[0]
+	require.Equal(t, "initcontainer:1", container.Image)
+	require.Equal(t, corev1.PullIfNotPresent, container.ImagePullPolicy)
+	require.False(t, *container.SecurityContext.AllowPrivilegeEscalation)
+	require.True(t, *container.SecurityContext.RunAsNonRoot)
+	require.Equal(t, []corev1.Capability{"ALL"}, container.SecurityContext.Capabilities.Drop)
+	require.Equal(t, resource.MustParse("10m"), container.Resources.Requests[corev1.ResourceCPU])
+	require.Equal(t, corev1.SeccompProfileRuntimeDefault,
+		podTemplate.Annotations[corev1.SeccompContainerAnnotationKeyPrefix+warmupContainerInjectNameUID])
+}
+
+func TestGenerateCronJob_MergesWarmUpContainerSettings(t *testing.T) {
+	runAsUser := int64(1000)
+	instance := generatePSCSpec()
+	instance.Spec.CronJob.Spec.JobTemplate.Spec.Template.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "team-registry"}}
+	instance.Spec.WarmUpContainer = &pscv1alpha1.WarmUpContainerTemplate{
+		Image: "mirror.local/initcontainer:1",
+		Resources: &corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("32Mi")},
+		},
+		SecurityContext: &corev1.SecurityContext{RunAsUser: &runAsUser},
+	}
+
+	r := newTestReconciler(t)
+	r.WarmUpContainer = pscv1alpha1.WarmUpContainerTemplate{ImagePullPolicy: corev1.PullAlways}
+	r.WarmUpImagePullSecrets = []corev1.LocalObjectReference{{Name: "operator-registry"}, {Name: "team-registry"}}
+
+	cron, err := r.generateCronJob(&instance)
+	require.NoError(t, err)
+
+	podSpec := cron.Spec.JobTemplate.Spec.Template.Spec
+	container := podSpec.InitContainers[0]
+	require.Equal(t, "mirror.local/initcontainer:1", container.Image)
+	require.Equal(t, corev1.PullAlways, container.ImagePullPolicy)
+
+	// overridden values replace the defaults, everything else is kept
+	require.Equal(t, resource.MustParse("32Mi"), container.Resources.Requests[corev1.ResourceMemory])
+	require.Equal(t, resource.MustParse("10m"), container.Resources.Requests[corev1.ResourceCPU])
+	require.Equal(t, runAsUser, *container.SecurityContext.RunAsUser)
+	require.True(t, *container.SecurityContext.RunAsNonRoot)
+
+	require.Equal(t, []corev1.LocalObjectReference{{Name: "team-registry"}, {Name: "operator-registry"}}, podSpec.ImagePullSecrets)
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	pscv1alpha1 "cronprimer.local/api/v1alpha1"
+	"github.com/robfig/cron/v3"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// WorkloadTimeAnnotation is set on primed pods and their jobs by the operator and holds the time their workload
+	// should start
+	WorkloadTimeAnnotation = "psc.cronprimer.local/workload-time"
+
+	workloadTimeVolume    = "psc-workload-time"
+	workloadTimeMountPath = "/etc/psc"
+	workloadTimeFile      = "workload-time"
+
+	// jobNameLabel is added to pods by the job controller
+	jobNameLabel = "job-name"
+)
+
+// workloadTimeVolumeSource exposes the workload time annotation to the warm-up container as a file, which the
+// kubelet updates once the operator has set the annotation. In barrier mode the barrier annotation is exposed too.
+func workloadTimeVolumeSource(barrier bool) corev1.Volume {
+	items := []corev1.DownwardAPIVolumeFile{
+		{
+			Path:     workloadTimeFile,
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: fmt.Sprintf("metadata.annotations['%s']", WorkloadTimeAnnotation)},
+		},
+	}
+	if barrier {
+		items = append(items, corev1.DownwardAPIVolumeFile{
+			Path:     barrierFile,
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: fmt.Sprintf("metadata.annotations['%s']", BarrierAnnotation)},
+		})
+	}
+
+	return corev1.Volume{
+		Name: workloadTimeVolume,
+		VolumeSource: corev1.VolumeSource{
+			DownwardAPI: &corev1.DownwardAPIVolumeSource{Items: items},
+		},
+	}
+}
+
+// minutesSuffixLimit separates the two job name suffixes, a time in minutes since the epoch stays below it
+// until the year 3871 while a time in seconds has been above it since 2001
+const minutesSuffixLimit = 1000000000
+
+// PrimerTimeFromJobName recovers the time a cronjob fired from the name of the job it created. The original
+// cronjob controller suffixes it with the scheduled time in seconds since the epoch, CronJobControllerV2
+// (the default from 1.21) with the scheduled time in minutes.
+func PrimerTimeFromJobName(cronJobName string, jobName string) (time.Time, error) {
+	prefix := cronJobName + "-"
+	if !strings.HasPrefix(jobName, prefix) {
+		return time.Time{}, fmt.Errorf("job %s was not created by cronjob %s", jobName, cronJobName)
+	}
+
+	suffix, err := strconv.ParseInt(strings.TrimPrefix(jobName, prefix), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("job %s has no scheduled time suffix: %s", jobName, err)
+	}
+	if suffix < minutesSuffixLimit {
+		return time.Unix(suffix*60, 0).UTC(), nil
+	}
+	return time.Unix(suffix, 0).UTC(), nil
+}
+
+// WorkloadTimeFor is when the workload of the run primed at primerAt should start
+func WorkloadTimeFor(instance *pscv1alpha1.PreScaledCronJob, primerAt time.Time) (time.Time, error) {
+	instance, err := singleSchedule(instance)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	schedule, err := cron.ParseStandard(instance.Spec.CronJob.Spec.Schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return schedule.Next(primerAt), nil
+}
+
+// jobNameFor finds the job which created a pod, preferring its controller reference
+func jobNameFor(pod *corev1.Pod) string {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "Job" && owner.Controller != nil && *owner.Controller {
+			return owner.Name
+		}
+	}
+	return pod.Labels[jobNameLabel]
+}
+
+// workloadTimeForPod works out when the workload of a primed pod should start from the job that created it,
+// so pods recreated by the job's backoff get the same time
+func workloadTimeForPod(pod *corev1.Pod, instance *pscv1alpha1.PreScaledCronJob) (time.Time, error) {
+	primerAt, err := PrimerTimeFromJobName(autogenName(instance), jobNameFor(pod))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return WorkloadTimeFor(instance, primerAt)
+}
+
+// PodWorkloadTime is when the workload of a primed pod was meant to start, read from the annotation the
+// operator stamped it with and worked out from its job for pods which haven't been stamped yet
+func PodWorkloadTime(pod *corev1.Pod, instance *pscv1alpha1.PreScaledCronJob) (time.Time, error) {
+	if stamp, stamped := pod.Annotations[WorkloadTimeAnnotation]; stamped {
+		workloadAt, err := time.Parse(time.RFC3339, stamp)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("pod %s has an invalid workload time: %s", pod.Name, err)
+		}
+		return workloadAt, nil
+	}
+	return workloadTimeForPod(pod, instance)
+}
+
+// stampWorkloadTime sets the workload time annotation on a primed pod, so the warm-up container knows when
+// to release without reading anything from the API, and on its job so the run can be followed once its pods
+// have gone
+func (r *PodReconciler) stampWorkloadTime(ctx context.Context, pod *corev1.Pod, workloadAt time.Time) error {
+	patch := client.MergeFrom(pod.DeepCopy())
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[WorkloadTimeAnnotation] = workloadAt.Format(time.RFC3339)
+	if err := r.Patch(ctx, pod, patch); err != nil {
+		return err
+	}
+
+	job := &batchv1.Job{}
+	if err := r.Get(ctx, types.NamespacedName{Name: jobNameFor(pod), Namespace: pod.Namespace}, job); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if _, stamped := job.Annotations[WorkloadTimeAnnotation]; stamped {
+		return nil
+	}
+
+	jobPatch := client.MergeFrom(job.DeepCopy())
+	if job.Annotations == nil {
+		job.Annotations = map[string]string{}
+	}
+	job.Annotations[WorkloadTimeAnnotation] = workloadAt.Format(time.RFC3339)
+	return r.Patch(ctx, job, jobPatch)
+}
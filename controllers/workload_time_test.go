@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newPrimedPod(jobName string, podName string) *corev1.Pod {
+	isController := true
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: namespace,
+			Labels:    map[string]string{PrimedCronLabel: "hourly", jobNameLabel: jobName},
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "batch/v1", Kind: "Job", Name: jobName, Controller: &isController},
+			},
+		},
+	}
+}
+
+func TestPrimerTimeFromJobName(t *testing.T) {
+	primerAt := time.Date(2020, 1, 29, 12, 20, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		name        string
+		cronJobName string
+		jobName     string
+		expected    time.Time
+		expectedErr bool
+	}{
+		{"suffix in seconds", "autogen-hourly", fmt.Sprintf("autogen-hourly-%d", primerAt.Unix()), primerAt, false},
+		{"suffix in minutes", "autogen-hourly", fmt.Sprintf("autogen-hourly-%d", primerAt.Unix()/60), primerAt, false},
+		{"job of another cronjob", "autogen-nightly", fmt.Sprintf("autogen-hourly-%d", primerAt.Unix()), time.Time{}, true},
+		{"manually created job", "autogen-hourly", "autogen-hourly-manual", time.Time{}, true},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			parsed, err := PrimerTimeFromJobName(scenario.cronJobName, scenario.jobName)
+			if scenario.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, scenario.expected, parsed)
+		})
+	}
+}
+
+func TestWorkloadTimeForPod_SameForRecreatedPods(t *testing.T) {
+	instance := newPreviewPSC("hourly", "30 * * * *", 10, "")
+	primerAt := time.Date(2020, 1, 29, 12, 20, 0, 0, time.UTC)
+	jobName := fmt.Sprintf("%s-%d", autogenName(&instance), primerAt.Unix())
+
+	// a pod recreated by the job's backoff long after the primer fired still targets the same run
+	for _, podName := range []string{jobName + "-abcde", jobName + "-fghij"} {
+		workloadAt, err := workloadTimeForPod(newPrimedPod(jobName, podName), &instance)
+		require.NoError(t, err)
+		require.Equal(t, time.Date(2020, 1, 29, 12, 30, 0, 0, time.UTC), workloadAt)
+	}
+}
+
+func TestStampWorkloadTime_AnnotatesPodAndJob(t *testing.T) {
+	pod := newPrimedPod("autogen-hourly-1580098800", "autogen-hourly-1580098800-abcde")
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "autogen-hourly-1580098800", Namespace: namespace}}
+	r := newTestPodReconciler(t, pod, job)
+	ctx := context.Background()
+	workloadAt := time.Date(2020, 1, 29, 12, 30, 0, 0, time.UTC)
+
+	require.NoError(t, r.stampWorkloadTime(ctx, pod.DeepCopy(), workloadAt))
+
+	stamped := &corev1.Pod{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, stamped))
+	require.Equal(t, "2020-01-29T12:30:00Z", stamped.Annotations[WorkloadTimeAnnotation])
+	stampedJob := &batchv1.Job{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, stampedJob))
+	require.Equal(t, "2020-01-29T12:30:00Z", stampedJob.Annotations[WorkloadTimeAnnotation])
+
+	// a job which has gone doesn't stop the pod being stamped
+	orphan := newPrimedPod("autogen-hourly-1580098860", "autogen-hourly-1580098860-abcde")
+	r.Client = newTestClient(t, orphan)
+	require.NoError(t, r.stampWorkloadTime(ctx, orphan.DeepCopy(), workloadAt))
+}
+
+func TestPodWorkloadTime_PrefersTheStamp(t *testing.T) {
+	instance := newPreviewPSC("hourly", "30 * * * *", 10, "")
+	primerAt := time.Date(2020, 1, 29, 12, 20, 0, 0, time.UTC)
+	jobName := fmt.Sprintf("%s-%d", autogenName(&instance), primerAt.Unix())
+	pod := newPrimedPod(jobName, jobName+"-abcde")
+
+	workloadAt, err := PodWorkloadTime(pod, &instance)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2020, 1, 29, 12, 30, 0, 0, time.UTC), workloadAt)
+
+	pod.Annotations = map[string]string{WorkloadTimeAnnotation: "2020-01-29T12:05:00Z"}
+	workloadAt, err = PodWorkloadTime(pod, &instance)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2020, 1, 29, 12, 5, 0, 0, time.UTC), workloadAt)
+
+	pod.Annotations[WorkloadTimeAnnotation] = "half past twelve"
+	_, err = PodWorkloadTime(pod, &instance)
+	require.Error(t, err)
+}
+
+func TestObservedTransitions_LateCreatedPod(t *testing.T) {
+	// the */30 primer for a 5/30 schedule fired at 12:00 but the pod wasn't created until 12:07, after the
+	// workload time, so the schedule's next run after creation would be 12:35 rather than the 12:05 it was for
+	instance := newPreviewPSC("psc-late", "5/30 * * * *", 0, "")
+	instance.Spec.PrimerSchedule = "*/30 * * * *"
+	primerAt := time.Date(2020, 1, 29, 12, 0, 0, 0, time.UTC)
+	jobName := fmt.Sprintf("%s-%d", autogenName(&instance), primerAt.Unix())
+	pod := newPrimedPod(jobName, jobName+"-x7k2p")
+	pod.CreationTimestamp = metav1.NewTime(time.Date(2020, 1, 29, 12, 7, 0, 0, time.UTC))
+
+	events := []corev1.Event{}
+	for _, name := range []string{"scheduledEvent", "initStartedEvent", "workloadPulledEvent", "workloadStartedEvent"} {
+		event := mustReadEventFromFile("../testdata/events/late-created/" + name + ".json")
+		require.Equal(t, pod.Name, event.InvolvedObject.Name)
+		events = append(events, event)
+	}
+
+	firedAt, err := PrimerTimeFromJobName(autogenName(&instance), jobName)
+	require.NoError(t, err)
+	require.Equal(t, primerAt, firedAt)
+
+	workloadAt, err := PodWorkloadTime(pod, &instance)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2020, 1, 29, 12, 5, 0, 0, time.UTC), workloadAt)
+
+	transitions, err := ObservedTransitions(events, pod, workloadAt)
+	require.NoError(t, err)
+	require.Equal(t, time.Second*2, transitions[timeToSchedule])
+	// the workload started at 12:08:10, 3m10s after the 12:05 it was primed for
+	require.Equal(t, time.Minute*3+time.Second*10, transitions[timeDelayOfWorkload])
+
+	// the operator's stamp is what the warm-up container released against, so it is what the delay is measured from
+	pod.Annotations = map[string]string{WorkloadTimeAnnotation: "2020-01-29T12:05:00Z"}
+	stampedAt, err := PodWorkloadTime(pod, &instance)
+	require.NoError(t, err)
+	require.Equal(t, workloadAt, stampedAt)
+
+	// without a workload time the other transitions are still reported
+	transitions, err = ObservedTransitions(events, pod, time.Time{})
+	require.Error(t, err)
+	require.Equal(t, time.Second*2, transitions[timeToSchedule])
+	_, measured := transitions[timeDelayOfWorkload]
+	require.False(t, measured)
+}
+
+func TestGenerateCronJob_MountsWorkloadTime(t *testing.T) {
+	instance := generatePSCSpec()
+	r := newTestReconciler(t)
+
+	cron, err := r.generateCronJob(&instance)
+	require.NoError(t, err)
+
+	podSpec := cron.Spec.JobTemplate.Spec.Template.Spec
+	require.Contains(t, podSpec.Volumes, workloadTimeVolumeSource(false))
+	container := podSpec.InitContainers[0]
+	require.Equal(t, workloadTimeVolume, container.VolumeMounts[0].Name)
+	require.Contains(t, container.Env, corev1.EnvVar{Name: "WORKLOAD_TIME_FILE", Value: "/etc/psc/workload-time"})
+}
@@ -1,41 +1,130 @@
 from croniter import croniter
-from datetime import datetime
-from kubernetes import client, config
+from datetime import datetime, timedelta, timezone
+from dateutil import tz
 import time
 import os
+import sys
 
-def get_pod_creation_date(podName, podNamespace):
-    try:
-        config.load_incluster_config()
-    except: 
-        config.load_kube_config()
+# exit code the operator recognises as a run which finished warming up later than its max lateness
+LATE_EXIT_CODE = 3
 
-    v1 = client.CoreV1Api()
-    podStatus = v1.read_namespaced_pod_status(name=podName, namespace=podNamespace)
-    return podStatus.metadata.creation_timestamp
+# how long to wait for the operator to stamp the pod with its workload time before falling back to the schedule
+STAMP_TIMEOUT_SECONDS = int(os.environ.get('STAMP_TIMEOUT_SECONDS', '120'))
 
-def wait_on_cron_schedule(creationDate, schedule):
-    if schedule:
-        if croniter.is_valid(schedule):
-            cron = croniter(schedule, creationDate)
-            nextdate = cron.get_next(datetime)
+def read_workload_time(workloadTimeFile):
+    # the operator sets an annotation on the pod which the kubelet writes to this file through the downward api,
+    # the file exists but is empty until the annotation has been set
+    deadline = time.monotonic() + STAMP_TIMEOUT_SECONDS
 
-            while True:
-                now = datetime.now().astimezone() # needs to be tz-aware to compare
+    while time.monotonic() < deadline:
+        try:
+            with open(workloadTimeFile) as f:
+                value = f.read().strip()
+            if value:
+                return datetime.strptime(value, "%Y-%m-%dT%H:%M:%SZ").replace(tzinfo=timezone.utc)
+        except FileNotFoundError:
+            pass
+        except ValueError:
+            print("invalid workload time in " + workloadTimeFile)
+            return None
 
-                if now >= nextdate:
-                    print("finally reached!")
-                    break
+        print("waiting for the workload time to be set")
+        time.sleep(5)
 
-                print("current time: " + now.strftime("%m/%d/%Y, %H:%M:%S"))    
-                print("didn't reach " + nextdate.strftime("%m/%d/%Y, %H:%M:%S"))
+    print("workload time was not set within " + str(STAMP_TIMEOUT_SECONDS) + " seconds")
+    return None
 
-                time.sleep(5)
-        else:
-            print("invalid cron schedule")
-    else:
+def next_scheduled_time(schedule, startedAt):
+    # the run is the first one after the pod started, not after any time spent waiting for the stamp
+    if not schedule:
         print("no cron schedule passed via env variables")
+        return None
+
+    # croniter doesn't understand the TZ= or CRON_TZ= prefix, the schedule is read in that time zone instead
+    zone = None
+    fields = schedule.split(None, 1)
+    if fields[0].startswith('TZ=') or fields[0].startswith('CRON_TZ='):
+        name = fields[0].split('=', 1)[1]
+        zone = tz.gettz(name)
+        if zone is None:
+            print("unknown time zone " + name)
+            return None
+        schedule = fields[1] if len(fields) > 1 else ''
+
+    if not croniter.is_valid(schedule):
+        print("invalid cron schedule")
+        return None
+
+    return croniter(schedule, startedAt.astimezone(zone)).get_next(datetime)
+
+def wait_until(nextdate):
+    while True:
+        now = datetime.now().astimezone() # needs to be tz-aware to compare
+
+        if now >= nextdate:
+            print("finally reached!")
+            break
+
+        print("current time: " + now.strftime("%m/%d/%Y, %H:%M:%S"))
+        print("didn't reach " + nextdate.strftime("%m/%d/%Y, %H:%M:%S"))
+
+        time.sleep(min(5, max((nextdate - now).total_seconds(), 0.1)))
+
+def wait_for_barrier(barrierFile, deadline):
+    # in barrier mode the operator marks every pod of the job released once they have all been scheduled,
+    # if that doesn't happen by the deadline the pod goes ahead on its own
+    while datetime.now().astimezone() < deadline:
+        try:
+            with open(barrierFile) as f:
+                if f.read().strip() == "released":
+                    print("released with the rest of the job")
+                    return
+        except FileNotFoundError:
+            pass
+
+        print("waiting for the rest of the job to be scheduled")
+        time.sleep(1)
+
+    print("barrier deadline passed, starting without the rest of the job")
+
+def too_late(nextdate, maxLateness):
+    # a pod scheduled long after its workload time, for example after an outage, must not start a stale run
+    lateness = datetime.now().astimezone() - nextdate
+    if lateness > maxLateness:
+        print("workload time " + nextdate.strftime("%m/%d/%Y, %H:%M:%S") + " passed " + str(lateness) +
+              " ago, more than the max lateness of " + str(maxLateness))
+        return True
+    return False
+
+def workload_time(startedAt):
+    nextdate = None
+
+    workloadTimeFile = os.environ.get('WORKLOAD_TIME_FILE')
+    if workloadTimeFile:
+        nextdate = read_workload_time(workloadTimeFile)
+
+    # without a workload time, for example when used outside the operator, wait for the next scheduled run
+    if nextdate is None:
+        nextdate = next_scheduled_time(os.environ.get('CRONJOB_SCHEDULE'), startedAt)
+    return nextdate
 
 if __name__ == '__main__':
-    creationDate = get_pod_creation_date(os.environ.get('HOSTNAME'), os.environ.get('NAMESPACE'))
-    wait_on_cron_schedule(creationDate, os.environ.get('CRONJOB_SCHEDULE'))
+    startedAt = datetime.now().astimezone()
+
+    # a prescaledcronjob with several schedules primes each with its own cronjob
+    scheduleName = os.environ.get('SCHEDULE_NAME')
+    if scheduleName:
+        print("warming up for schedule " + scheduleName)
+
+    nextdate = workload_time(startedAt)
+    if nextdate is not None:
+        wait_until(nextdate)
+
+        barrierFile = os.environ.get('BARRIER_FILE')
+        if barrierFile:
+            timeout = int(os.environ.get('BARRIER_TIMEOUT_SECONDS', '300'))
+            wait_for_barrier(barrierFile, nextdate + timedelta(seconds=timeout))
+
+        maxLateness = os.environ.get('MAX_LATENESS_SECONDS')
+        if maxLateness and too_late(nextdate, timedelta(seconds=int(maxLateness))):
+            sys.exit(LATE_EXIT_CODE)
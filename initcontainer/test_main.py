@@ -0,0 +1,63 @@
+import os
+import tempfile
+import unittest
+from datetime import datetime, timedelta, timezone
+from unittest import mock
+
+import main
+
+class FakeClock:
+    # time only moves on when the script sleeps, so waiting out the stamp timeout is instant
+    def __init__(self, now):
+        self.now = now
+        self.elapsed = 0
+
+    def monotonic(self):
+        return self.elapsed
+
+    def sleep(self, seconds):
+        self.elapsed += seconds
+        self.now += timedelta(seconds=seconds)
+
+class WorkloadTimeTest(unittest.TestCase):
+    def setUp(self):
+        # the kubelet creates the file straight away but leaves it empty until the operator stamps the pod
+        f = tempfile.NamedTemporaryFile(delete=False)
+        f.close()
+        self.workloadTimeFile = f.name
+        self.addCleanup(os.remove, f.name)
+
+    def test_stamp_timeout_falls_back_to_the_run_after_the_pod_started(self):
+        startedAt = datetime(2020, 1, 27, 12, 4, tzinfo=timezone.utc)
+        clock = FakeClock(startedAt)
+        env = {'WORKLOAD_TIME_FILE': self.workloadTimeFile, 'CRONJOB_SCHEDULE': '*/5 * * * *'}
+
+        with mock.patch.dict(os.environ, env), \
+                mock.patch.object(main.time, 'monotonic', clock.monotonic), \
+                mock.patch.object(main.time, 'sleep', clock.sleep):
+            nextdate = main.workload_time(startedAt)
+
+        # the stamp never arrived, so the wait took the whole timeout and went past 12:05
+        self.assertGreaterEqual(clock.elapsed, main.STAMP_TIMEOUT_SECONDS)
+        self.assertGreater(clock.now, datetime(2020, 1, 27, 12, 5, tzinfo=timezone.utc))
+        self.assertEqual(nextdate, datetime(2020, 1, 27, 12, 5, tzinfo=timezone.utc))
+
+    def test_stamp_is_used_when_set(self):
+        with open(self.workloadTimeFile, 'w') as f:
+            f.write("2020-01-27T12:10:00Z")
+        env = {'WORKLOAD_TIME_FILE': self.workloadTimeFile, 'CRONJOB_SCHEDULE': '*/5 * * * *'}
+
+        with mock.patch.dict(os.environ, env):
+            nextdate = main.workload_time(datetime(2020, 1, 27, 12, 3, tzinfo=timezone.utc))
+
+        self.assertEqual(nextdate, datetime(2020, 1, 27, 12, 10, tzinfo=timezone.utc))
+
+    def test_schedule_time_zone_prefix(self):
+        startedAt = datetime(2020, 1, 27, 12, 3, tzinfo=timezone.utc)
+
+        nextdate = main.next_scheduled_time('CRON_TZ=Europe/London */5 * * * *', startedAt)
+
+        self.assertEqual(nextdate, datetime(2020, 1, 27, 12, 5, tzinfo=timezone.utc))
+
+if __name__ == '__main__':
+    unittest.main()
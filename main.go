@@ -1,26 +1,36 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	configv1alpha1 "cronprimer.local/api/config/v1alpha1"
 	pscv1alpha1 "cronprimer.local/api/v1alpha1"
 	"cronprimer.local/controllers"
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
 	// +kubebuilder:scaffold:imports
 )
 
-const (
-	initContainerEnvVariable = "INIT_CONTAINER_IMAGE"
-	defaultContainerImage    = "initcontainer:1"
-)
+const initContainerEnvVariable = "INIT_CONTAINER_IMAGE"
 
 var (
 	scheme   = runtime.NewScheme()
@@ -42,43 +52,174 @@ func init() {
 }
 
 func main() {
+	var configFile string
 	var metricsAddr string
+	var probeAddr string
+	var webhookPort int
 	var enableLeaderElection bool
+	var enableWebhooks bool
+	var dryRun bool
+	var namespaces string
+	var initContainerImage string
+	var nodepoolLabel string
+	var eventTrackingTTL time.Duration
+	var logLevel string
+	var logEncoding string
+	flag.StringVar(&configFile, "config", "",
+		"Path to an OperatorConfig file. Flags and the INIT_CONTAINER_IMAGE environment variable take precedence over it.")
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&probeAddr, "probe-addr", ":8081", "The address the probe and preview endpoints bind to.")
+	flag.IntVar(&webhookPort, "webhook-port", 9443, "The port the webhook server listens on.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Serve the validating webhook for PreScaledCronJobs. Requires serving certificates to be mounted for the webhook server.")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Report what would be done to cronjobs through events, status and metrics without creating, updating or deleting anything.")
+	flag.StringVar(&namespaces, "namespaces", "",
+		"Comma separated list of namespaces the manager is restricted to. Every namespace is watched when empty.")
+	flag.StringVar(&initContainerImage, "init-container-image", "initcontainer:1", "The image of the injected warm-up container.")
+	flag.StringVar(&nodepoolLabel, "nodepool-label", "agentpool", "The node selector key used to find the nodepool a pod targets.")
+	flag.DurationVar(&eventTrackingTTL, "event-tracking-ttl", time.Minute*75, "How long the last processed event of a pod is remembered.")
+	flag.StringVar(&logLevel, "log-level", "info",
+		"The level to log at, one of debug, info or error, or a verbosity such as 2 to include V(2) lines.")
+	flag.StringVar(&logEncoding, "log-encoding", "json", "How log lines are written, either json or console.")
 	flag.Parse()
 
-	logger := zap.Logger(true)
+	logger, err := newLogger(logLevel, logEncoding)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid logging flags: %s\n", err)
+		os.Exit(1)
+	}
 	ctrl.SetLogger(logger)
 
-	setupProbes()
+	config, err := configv1alpha1.Load(configFile)
+	if err != nil {
+		setupLog.Error(err, "unable to load operator config", "path", configFile)
+		os.Exit(1)
+	}
+
+	// only flags which were passed override the file, their defaults match the config defaults
+	overrides := func(c *configv1alpha1.OperatorConfig) {
+		if image := os.Getenv(initContainerEnvVariable); image != "" {
+			c.InitContainer.Image = image
+		}
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "metrics-addr":
+				c.Metrics.BindAddress = metricsAddr
+			case "probe-addr":
+				c.Health.ProbeBindAddress = probeAddr
+			case "webhook-port":
+				c.Webhook.Port = webhookPort
+			case "enable-leader-election":
+				c.LeaderElection.LeaderElect = enableLeaderElection
+			case "enable-webhooks":
+				c.Webhook.Enabled = enableWebhooks
+			case "dry-run":
+				c.DryRun = dryRun
+			case "namespaces":
+				c.Namespaces = splitNamespaces(namespaces)
+			case "init-container-image":
+				c.InitContainer.Image = initContainerImage
+			case "nodepool-label":
+				c.NodepoolLabel = nodepoolLabel
+			case "event-tracking-ttl":
+				c.EventTrackingTTL.Duration = eventTrackingTTL
+			}
+		})
+	}
+	overrides(config)
+
+	if err := config.Validate(); err != nil {
+		setupLog.Error(err, "invalid operator config")
+		os.Exit(1)
+	}
+	setupLog.Info("effective operator config", "config", config)
+
+	controllers.SetTunables(controllers.TunablesFromConfig(config))
+	buckets := config.Metrics.TimingBuckets
+	controllers.SetTimingBuckets(prometheus.ExponentialBuckets(buckets.Start, buckets.Factor, buckets.Count))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	setupProbes(config.Health.ProbeBindAddress)
+
+	watchNamespaces := config.Namespaces
+	options := ctrl.Options{
 		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		LeaderElection:     enableLeaderElection,
-		Port:               9443,
-	})
+		MetricsBindAddress: config.Metrics.BindAddress,
+		LeaderElection:     config.LeaderElection.LeaderElect,
+		Port:               config.Webhook.Port,
+	}
+	switch len(watchNamespaces) {
+	case 0:
+		setupLog.Info("watching all namespaces")
+	case 1:
+		options.Namespace = watchNamespaces[0]
+	default:
+		options.NewCache = cache.MultiNamespacedCacheBuilder(watchNamespaces)
+	}
+	if len(watchNamespaces) > 0 {
+		setupLog.Info("restricting manager to namespaces", "namespaces", watchNamespaces)
+	}
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), options)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	initContainerImage := os.Getenv(initContainerEnvVariable)
+	// cluster scoped policies and namespaces can't come from a namespaced cache so read them directly
+	var policyReader client.Reader
+	if len(watchNamespaces) > 0 {
+		policyReader = mgr.GetAPIReader()
+	}
+
+	// only pods labelled by the operator are cached, rather than every pod in the cluster
+	primedPods := controllers.NewPrimedPodCache(kubernetes.NewForConfigOrDie(mgr.GetConfig()), watchNamespaces, 0)
+	if err = mgr.Add(primedPods); err != nil {
+		setupLog.Error(err, "unable to add primed pod cache")
+		os.Exit(1)
+	}
+
+	// serve the fire time preview alongside the probes, reading from the manager's cache
+	(&controllers.PreviewHandler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("preview"),
+	}).Register(http.DefaultServeMux)
 
-	if initContainerImage == "" {
-		setupLog.Info(fmt.Sprintf("%s not set, using default", initContainerEnvVariable))
-		initContainerImage = defaultContainerImage
+	// runs are only traced when there's a collector to send them to
+	var tracerProvider *sdktrace.TracerProvider
+	var runTracer trace.Tracer
+	if config.Tracing.Endpoint != "" {
+		tracerProvider, err = controllers.NewTracerProvider(context.Background(), config.Tracing)
+		if err != nil {
+			setupLog.Error(err, "unable to set up tracing", "endpoint", config.Tracing.Endpoint)
+			os.Exit(1)
+		}
+		runTracer = controllers.RunTracer(tracerProvider)
+		setupLog.Info("tracing runs", "endpoint", config.Tracing.Endpoint)
 	}
 
-	setupLog.Info(fmt.Sprintf("Using image %s for initContainer", initContainerImage))
+	setupLog.Info("using warm-up container image", "image", config.InitContainer.Image)
+	if config.DryRun {
+		setupLog.Info("running in dry-run mode, cronjobs, jobs and pods will not be changed")
+	}
 
 	if err = (&controllers.PreScaledCronJobReconciler{
 		Client:             mgr.GetClient(),
+		Scheme:             mgr.GetScheme(),
 		Log:                ctrl.Log.WithName("controllers").WithName("prescaledcronjob"),
 		Recorder:           mgr.GetEventRecorderFor("prescaledcronjob-controller"),
-		InitContainerImage: initContainerImage,
+		InitContainerImage: config.InitContainer.Image,
+		WarmUpContainer: pscv1alpha1.WarmUpContainerTemplate{
+			ImagePullPolicy: config.InitContainer.ImagePullPolicy,
+			Resources:       config.InitContainer.Resources,
+			SecurityContext: config.InitContainer.SecurityContext,
+		},
+		WarmUpImagePullSecrets: config.InitContainer.ImagePullSecrets,
+		Pods:                   primedPods,
+		PolicyReader:           policyReader,
+		DryRun:                 config.DryRun,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "prescaledcronjob")
 		os.Exit(1)
@@ -88,21 +229,117 @@ func main() {
 		Client:             mgr.GetClient(),
 		Log:                ctrl.Log.WithName("controllers").WithName("pod"),
 		Recorder:           mgr.GetEventRecorderFor("pod-controller"),
-		InitContainerImage: initContainerImage,
+		InitContainerImage: config.InitContainer.Image,
+		Pods:               primedPods,
+		DryRun:             config.DryRun,
+		Tracer:             runTracer,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "pod")
 		os.Exit(1)
 	}
+
+	if err = (&controllers.JobReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("job"),
+		Recorder: mgr.GetEventRecorderFor("job-controller"),
+		Pods:     primedPods,
+		DryRun:   config.DryRun,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "job")
+		os.Exit(1)
+	}
+	if config.Webhook.Enabled {
+		validatorReader := client.Reader(mgr.GetClient())
+		if policyReader != nil {
+			validatorReader = policyReader
+		}
+		if err = (&controllers.PreScaledCronJobValidator{
+			Client: validatorReader,
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "prescaledcronjob")
+			os.Exit(1)
+		}
+	}
+
+	if err = mgr.Add(&controllers.CapacityPlanner{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("capacityplanner"),
+		Recorder: mgr.GetEventRecorderFor("capacity-planner"),
+	}); err != nil {
+		setupLog.Error(err, "unable to create capacity planner")
+		os.Exit(1)
+	}
+
+	if configFile != "" {
+		if err = mgr.Add(&controllers.ConfigWatcher{
+			Path:      configFile,
+			Log:       ctrl.Log.WithName("config"),
+			Current:   config,
+			Overrides: overrides,
+		}); err != nil {
+			setupLog.Error(err, "unable to create config watcher")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctrl.SetupSignalHandler())
+
+	// send the traces still waiting to be batched before exiting
+	if tracerProvider != nil {
+		if shutdownErr := tracerProvider.Shutdown(context.Background()); shutdownErr != nil {
+			setupLog.Error(shutdownErr, "problem flushing traces")
+		}
+	}
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
 
-func setupProbes() {
+// newLogger builds the manager's logger. The level is a zap level name or a logr verbosity, so 1 logs
+// V(1) lines as well as info lines.
+func newLogger(level string, encoding string) (logr.Logger, error) {
+	zapLevel := zapcore.InfoLevel
+	if verbosity, err := strconv.Atoi(level); err == nil {
+		if verbosity < 0 {
+			return nil, fmt.Errorf("log level %d must not be negative", verbosity)
+		}
+		zapLevel = zapcore.Level(-verbosity)
+	} else if err := zapLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, err
+	}
+	atomicLevel := zap.NewAtomicLevelAt(zapLevel)
+
+	var encoder zapcore.Encoder
+	switch encoding {
+	case "json":
+		encoder = zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	case "console":
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	default:
+		return nil, fmt.Errorf("log encoding %q must be json or console", encoding)
+	}
+
+	return crzap.New(func(o *crzap.Options) {
+		o.Level = &atomicLevel
+		o.Encoder = encoder
+	}), nil
+}
+
+// splitNamespaces turns the comma separated namespaces flag into a list, dropping empty entries
+func splitNamespaces(value string) []string {
+	namespaces := []string{}
+	for _, namespace := range strings.Split(value, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+func setupProbes(addr string) {
 	setupLog.Info("setting up probes")
 	started := time.Now()
 
@@ -126,8 +363,8 @@ func setupProbes() {
 	})
 
 	go func() {
-		setupLog.Info("probes are starting to listen", "addr", ":8081")
-		err := http.ListenAndServe(":8081", nil)
+		setupLog.Info("probes are starting to listen", "addr", addr)
+		err := http.ListenAndServe(addr, nil)
 		if err != nil {
 			setupLog.Error(err, "problem setting up probes")
 		}